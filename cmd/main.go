@@ -1,23 +1,212 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"syscall"
 
 	"github.com/ihrk/microbot/internal/app"
+	"github.com/ihrk/microbot/internal/creds"
 )
 
+// version is set at build time:
+//
+//	go build -ldflags "-X main.version=v1.2.3" ./cmd
+var version = "dev"
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"run", "connect to chat and run the bot", run},
+	{"validate", "check config and creds without connecting", validate},
+	{"simulate", "pass chat lines from stdin to handlers and print responses", simulate},
+	{"replay", "pass recorded chat lines to handlers and print responses", replay},
+	{"print-config", "print resolved config with secrets masked", printConfig},
+	{"version", "print version", printVersion},
+}
+
 func main() {
+	args := os.Args[1:]
+
+	// flags without command run the bot as before subcommands
+	name := "run"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		if err := cmd.run(args); err != nil {
+			log.Fatalf("%s: %v\n", name, err)
+		}
+
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: microbot <command> [flags]\n\ncommands:")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s%s\n", cmd.name, cmd.usage)
+	}
+
+	fmt.Fprintln(os.Stderr, "\nrun microbot <command> -h for flags of command")
+}
+
+// options are flags shared by commands that read config.
+type options struct {
+	configPath string
+	credsOpts  creds.Options
+}
+
+func newFlagSet(name string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	fs.StringVar(&opts.configPath, "config", "./config.yml", "path to configuration file")
+	fs.StringVar(&opts.credsOpts.File, "creds", "./creds.yml", "path to file with creds")
+	fs.StringVar(&opts.credsOpts.SecretsDir, "secrets", "", "path to directory with secret files named by cred keys")
+
+	return fs
+}
+
+// parse parses args, default creds file may be absent
+// if creds are passed by other sources.
+func parse(fs *flag.FlagSet, opts *options, args []string) {
+	fs.Parse(args)
+
+	opts.credsOpts.FileOptional = true
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "creds" {
+			opts.credsOpts.FileOptional = false
+		}
+	})
+}
+
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+func run(args []string) error {
+	var opts options
+
+	parse(newFlagSet("run", &opts), &opts, args)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	err := app.LoadConfigAndRun(ctx, opts.configPath, opts.credsOpts)
+	if err != nil {
+		return fmt.Errorf("bot stopped with error: %w", err)
+	}
+
+	log.Println("bot stopped")
+
+	return nil
+}
+
+func validate(args []string) error {
+	var opts options
+
+	parse(newFlagSet("validate", &opts), &opts, args)
+
+	err := app.Validate(opts.configPath, opts.credsOpts)
+	if err != nil {
+		return fmt.Errorf("config is invalid:\n%w", err)
+	}
+
+	fmt.Println("config is valid")
+
+	return nil
+}
+
+func simulate(args []string) error {
+	var opts options
+
+	parse(newFlagSet("simulate", &opts), &opts, args)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	return app.Simulate(ctx, opts.configPath, opts.credsOpts, os.Stdin, os.Stdout)
+}
+
+func replay(args []string) error {
 	var (
-		configPath string
-		credsPath  string
+		opts  options
+		speed float64
 	)
 
-	flag.StringVar(&configPath, "config", "./config.yml", "path to configuration file")
-	flag.StringVar(&credsPath, "creds", "./creds.yml", "path to file with creds")
+	fs := newFlagSet("replay", &opts)
+	fs.Float64Var(&speed, "speed", 1, "replay speed relative to original, 0 means without delays")
+
+	parse(fs, &opts, args)
+
+	if fs.NArg() == 0 {
+		return errors.New("record files are required: microbot replay [flags] record-file...")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	return app.Replay(ctx, opts.configPath, opts.credsOpts, fs.Args(), speed, os.Stdout)
+}
+
+func printConfig(args []string) error {
+	var opts options
+
+	parse(newFlagSet("print-config", &opts), &opts, args)
+
+	return app.PrintConfig(opts.configPath, opts.credsOpts, os.Stdout)
+}
+
+func printVersion(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	fs.Parse(args)
+
+	v := version
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		bi = new(debug.BuildInfo)
+	}
+
+	// binaries installed by go install have module version
+	if v == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		v = bi.Main.Version
+	}
+
+	fmt.Printf("microbot %s %s %s/%s\n", v, runtime.Version(), runtime.GOOS, runtime.GOARCH)
 
-	flag.Parse()
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			fmt.Printf("%s: %s\n", s.Key, s.Value)
+		}
+	}
 
-	log.Fatalf("dial failed with error: %v\n",
-		app.LoadConfigAndRun(configPath, credsPath))
+	return nil
 }
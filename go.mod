@@ -5,8 +5,11 @@ go 1.17
 require (
 	github.com/disintegration/gift v1.2.1
 	github.com/ihrk/dots v0.1.0
-	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 	nhooyr.io/websocket v1.8.7
 )
 
-require github.com/klauspost/compress v1.13.6 // indirect
+require (
+	github.com/klauspost/compress v1.13.6 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
@@ -2,75 +2,265 @@ package app
 
 import (
 	"context"
+	"errors"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/ihrk/microbot/internal/backoff"
 	"github.com/ihrk/microbot/internal/bot"
 	"github.com/ihrk/microbot/internal/config"
 	"github.com/ihrk/microbot/internal/creds"
+	"github.com/ihrk/microbot/internal/extra/twitch"
 	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/record"
+	"github.com/ihrk/microbot/internal/store"
 )
 
 const (
-	dialTimeout    = 10 * time.Second
-	retryLim       = 10
-	initialBackoff = 2 * time.Second
+	dialTimeout = 10 * time.Second
+
+	// drainTimeout limits time of handling messages received
+	// before shutdown and sending responses.
+	drainTimeout = 10 * time.Second
+
+	// stopTimeout limits time of waiting for handlers
+	// cancelled after drainTimeout.
+	stopTimeout = 5 * time.Second
 )
 
-func LoadConfigAndRun(configPath, credsPath string) error {
+// reconnectPolicy is unlimited, delays are reset after connection
+// has been working for a minute.
+var reconnectPolicy = backoff.Policy{
+	Min:        2 * time.Second,
+	Max:        2 * time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+	ResetAfter: time.Minute,
+}
+
+// LoadConfigAndRun runs bot until ctx is done, nil
+// is returned after graceful shutdown.
+func LoadConfigAndRun(ctx context.Context, configPath string, credsOpts creds.Options) error {
 	cfg, err := config.Read(configPath)
 	if err != nil {
 		return err
 	}
 
-	err = creds.Load(credsPath)
+	cr, err := creds.Load(credsOpts)
+	if err != nil {
+		return err
+	}
+
+	if anonymous(cr) {
+		log.Println("twitch creds are not set, chat is read anonymously and messages are not sent")
+	}
+
+	db, err := store.Open(cfg.Store)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	// creds of actions are checked when actions are built,
+	// login creds are checked here
+	tokens, err := newTokenSource(cr, db)
+	if err != nil {
+		return err
+	}
+
+	if tokens != nil {
+		err = tokens.Start(ctx)
+		if err != nil {
+			return err
+		}
+
+		go tokens.Run(ctx)
+	}
+
+	s, err := loadConfig(cfg, db, cr)
 	if err != nil {
 		return err
 	}
 
-	return loadConfig(cfg).run(context.Background())
+	var rec *record.Writer
+
+	if r := cfg.Record; r.Path != "" {
+		rec, err = record.Open(r.Path, int64(r.MaxSize)<<20, r.MaxFiles)
+		if err != nil {
+			return err
+		}
+
+		defer rec.Close()
+	}
+
+	opts := s.opts
+	opts.ReadOnly = anonymous(cr)
+
+	a := &app{
+		configPath: configPath,
+		chatURL:    irc.ChatURL,
+		storePath:  cfg.Store,
+		workers:    cfg.Workers,
+		record:     cfg.Record,
+		db:         db,
+		creds:      cr,
+		tokens:     tokens,
+		rec:        rec,
+		srv:        bot.NewServer(s.h, opts),
+	}
+
+	a.apply(ctx, s)
+
+	go a.watch(ctx)
+
+	err = a.run(ctx)
+
+	// store is closed only after handlers cancelled by shutdown return,
+	// writes of handlers that are still running are refused by it
+	a.waitHandlers()
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
+
+func (a *app) waitHandlers() {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+
+	if err := a.srv.Wait(ctx); err != nil {
+		log.Printf("handlers are still running, their store writes are refused: %v\n", err)
+	}
 }
 
 type app struct {
+	configPath string
+	chatURL    string
+	storePath  string
+	workers    config.Workers // settings srv is created with
+	record     config.Record  // settings rec is opened with
+	db         store.DB
+	creds      *creds.Creds
+	tokens     *twitch.TokenSource // nil if static token is used
+	rec        *record.Writer      // nil if chat is not recorded
+	srv        *bot.Server
+
+	m          sync.Mutex
+	setup      *setup
+	client     *irc.Client // nil while disconnected
+	stopTimers context.CancelFunc
+}
+
+// setup is everything built from config.
+type setup struct {
 	h        bot.Handler
+	sched    *scheduler
 	channels []string
+	opts     bot.Options // used only at start, changes require restart
 }
 
-func loadConfig(cfg *config.App) *app {
-	var a app
+// loadConfig builds handlers of config, all found errors
+// are returned as config.Errors.
+func loadConfig(cfg *config.App, db store.DB, cr *creds.Creds) (*setup, error) {
+	var s setup
 
 	for _, ch := range cfg.Channels {
-		a.channels = append(a.channels, ch.Name)
+		s.channels = append(s.channels, ch.Name)
 	}
 
-	a.h = appHandler(cfg)
+	b := builder{cfg: cfg, db: db, creds: cr}
+
+	s.opts = b.serverOptions()
+	b.checkRecord()
 
-	return &a
+	h := b.appHandler()
+
+	s.sched = b.scheduler()
+	s.h = bot.Wrap(h, s.sched.count)
+
+	if err := b.errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
 }
 
 func (a *app) run(ctx context.Context) error {
 	var (
-		client *irc.Client
-		err    error
+		client    *irc.Client
+		prev      *irc.Client
+		err       error
+		refreshed bool // token is refreshed after login failure
 	)
 
+	b := backoff.New(reconnectPolicy)
+
 	for {
-		err = backoff.RunWithRetry(retryLim, initialBackoff,
-			func() error {
-				var dialErr error
-				log.Println("attempting to dial...")
-				client, dialErr = irc.Dial(ctx, dialTimeout)
-				return dialErr
-			})
-		if err != nil {
-			return err
+		for {
+			log.Println("attempting to dial...")
+
+			client, err = irc.DialURL(ctx, a.chatURL, dialTimeout)
+			if err == nil {
+				break
+			}
+
+			log.Printf("dial error: %v\n", err)
+
+			if err = b.Wait(ctx); err != nil {
+				return err
+			}
 		}
 
 		log.Println("dial is successful")
 
+		if a.rec != nil {
+			client.SetRecorder(a.rec)
+		}
+
+		b.Succeeded()
+
+		if prev != nil {
+			client.Adopt(prev)
+		}
+
 		err = a.listenAndServe(ctx, client)
-		log.Printf("connection interrupted with error: %v\n", err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		switch {
+		case errors.Is(err, irc.ErrLoginFailed):
+			if a.tokens == nil || refreshed {
+				return err
+			}
+
+			log.Printf("%v, refreshing token\n", err)
+
+			if err = a.tokens.Refresh(ctx); err != nil {
+				return err
+			}
+
+			refreshed = true
+			prev = client
+
+			continue
+		case errors.Is(err, irc.ErrReconnect):
+			log.Println("server requested reconnect")
+		default:
+			log.Printf("connection interrupted with error: %v\n", err)
+
+			if err = b.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		refreshed = false
+
+		prev = client
 	}
 }
 
@@ -82,17 +272,58 @@ func (a *app) listenAndServe(ctx context.Context, c *irc.Client) error {
 		return err
 	}
 
-	err = c.Login(creds.TwitchUser(), creds.TwitchPass())
+	nick, pass, err := a.login(ctx)
 	if err != nil {
 		return err
 	}
 
-	for _, channel := range a.channels {
-		err = c.Join(channel)
-		if err != nil {
-			return err
+	err = c.Login(nick, pass)
+	if err != nil {
+		return err
+	}
+
+	err = a.joinAll(c)
+	if err != nil {
+		return err
+	}
+
+	defer a.setClient(nil)
+
+	err = a.srv.ListenAndServe(ctx, c)
+	if ctx.Err() != nil {
+		a.shutdown(c)
+	}
+
+	return err
+}
+
+// shutdown sends responses of running handlers and leaves channels.
+func (a *app) shutdown(c *irc.Client) {
+	log.Println("shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := a.srv.Drain(ctx, c); err != nil {
+		log.Printf("handlers are not finished: %v\n", err)
+	}
+
+	if err := c.Flush(ctx); err != nil {
+		log.Printf("send queue is not flushed, %d messages lost: %v\n",
+			c.Stats().Depth, err)
+	}
+
+	a.m.Lock()
+	channels := a.setup.channels
+	a.m.Unlock()
+
+	for _, channel := range channels {
+		if err := c.Part(channel); err != nil {
+			log.Printf("part %s error: %v\n", channel, err)
 		}
 	}
 
-	return bot.NewServer(c, a.h).ListenAndServe(ctx)
+	if err := c.Quit(); err != nil {
+		log.Printf("quit error: %v\n", err)
+	}
 }
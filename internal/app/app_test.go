@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ihrk/microbot/internal/bot"
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/creds"
+	"github.com/ihrk/microbot/internal/store"
+)
+
+func assertEq(t *testing.T, expected, actual interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nexpected: '%v',\nactual: '%v'", expected, actual)
+	}
+}
+
+const invalidConfig = `channels:
+  - name: "#first"
+    chat:
+      commands:
+        - key: hello
+          action:
+            type: print
+            settings:
+              text: hello
+        - key: "!cd"
+          action:
+            type: print
+            settings:
+              text: 42
+      middlewares:
+        - type: filter
+          settings:
+            type: unknown
+  - name: "#second"
+    chat:
+      rewards:
+        - key: id
+      timers:
+        - interval: 10m
+`
+
+func TestLoadConfigErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := os.WriteFile(path, []byte(invalidConfig), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = loadConfig(cfg, store.NewMemory(), new(creds.Creds))
+
+	var errs config.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected config.Errors, got: %v", err)
+	}
+
+	var located []string
+	for _, err := range errs {
+		var e *config.Error
+		if !errors.As(err, &e) {
+			t.Fatalf("expected config.Error, got: %v", err)
+		}
+
+		located = append(located, e.Path)
+
+		if e.Line == 0 {
+			t.Errorf("line not found for %s", e.Path)
+		}
+	}
+
+	assertEq(t, []string{
+		"channels[0].chat.commands[1].key",
+		"channels[0].chat.commands[1].action.settings.text",
+		"channels[0].chat.middlewares[0].settings.type",
+		"channels[1].chat.rewards[0].action",
+		"channels[1].chat.timers[0].messages",
+	}, located)
+}
+
+func TestReloadKeepsConfigOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	write := func(text string) {
+		t.Helper()
+
+		err := os.WriteFile(path, []byte(text), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("channels:\n  - name: first\n")
+
+	a := &app{
+		configPath: path,
+		storePath:  "./store.log",
+		db:         store.NewMemory(),
+		creds:      new(creds.Creds),
+		srv:        bot.NewServer(bot.NewMux(), bot.Options{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a.reload(ctx)
+	assertEq(t, []string{"first"}, a.setup.channels)
+
+	write(invalidConfig)
+	a.reload(ctx)
+	assertEq(t, []string{"first"}, a.setup.channels)
+
+	write("channels:\n  - name: first\n  - name: second\n")
+	a.reload(ctx)
+	assertEq(t, []string{"first", "second"}, a.setup.channels)
+
+	var logs strings.Builder
+
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	// config is applied, but workers are kept until restart
+	write("workers:\n  count: 2\nchannels:\n  - name: first\n")
+	a.reload(ctx)
+	assertEq(t, []string{"first"}, a.setup.channels)
+	assertEq(t, true, strings.Contains(logs.String(), "workers settings can not be changed without restart"))
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+
+	"github.com/ihrk/microbot/internal/creds"
+	"github.com/ihrk/microbot/internal/extra/twitch"
+	"github.com/ihrk/microbot/internal/store"
+)
+
+const (
+	// authNamespace can not clash with channel names.
+	authNamespace = "@auth"
+	tokenKey      = "twitch/token"
+)
+
+// tokenStore keeps rotated twitch token in store, token is
+// discarded if refresh token of creds is changed since it was saved.
+// Seed is a hash of refresh token of creds, so it is not written
+// to store as is.
+type tokenStore struct {
+	s    store.Store
+	seed string
+}
+
+func tokenSeed(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+type storedToken struct {
+	twitch.Token
+	Seed string `json:"seed"`
+}
+
+func (ts *tokenStore) Load() (*twitch.Token, error) {
+	v, ok := ts.s.Get(tokenKey)
+	if !ok {
+		return nil, nil
+	}
+
+	var st storedToken
+
+	err := json.Unmarshal([]byte(v), &st)
+	if err != nil || st.Seed != ts.seed {
+		return nil, err
+	}
+
+	return &st.Token, nil
+}
+
+func (ts *tokenStore) Save(tok *twitch.Token) error {
+	data, err := json.Marshal(storedToken{Token: *tok, Seed: ts.seed})
+	if err != nil {
+		return err
+	}
+
+	return ts.s.Set(tokenKey, string(data))
+}
+
+// anonymous reports whether creds have no twitch login,
+// chat is read anonymously then.
+func anonymous(cr *creds.Creds) bool {
+	return !cr.Has(creds.KeyTwitchUser) &&
+		!cr.Has(creds.KeyTwitchPass) &&
+		!cr.Has(creds.KeyTwitchRefreshToken)
+}
+
+// newTokenSource returns nil if creds have no refresh token,
+// static twitchpass or anonymous login is used then.
+func newTokenSource(cr *creds.Creds, db store.DB) (*twitch.TokenSource, error) {
+	if anonymous(cr) {
+		return nil, nil
+	}
+
+	if !cr.Has(creds.KeyTwitchRefreshToken) {
+		return nil, cr.Require(creds.KeyTwitchUser, creds.KeyTwitchPass)
+	}
+
+	err := cr.Require(creds.KeyTwitchClientID, creds.KeyTwitchClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, _ := cr.Get(creds.KeyTwitchRefreshToken)
+	clientID, _ := cr.Get(creds.KeyTwitchClientID)
+	clientSecret, _ := cr.Get(creds.KeyTwitchClientSecret)
+
+	c := &twitch.AuthClient{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}
+
+	ts := &tokenStore{
+		s:    db.Namespace(authNamespace),
+		seed: tokenSeed(refreshToken),
+	}
+
+	return twitch.NewTokenSource(c, ts, refreshToken), nil
+}
+
+// login returns nick and password for IRC login.
+func (a *app) login(ctx context.Context) (string, string, error) {
+	if anonymous(a.creds) {
+		// any password is accepted for justinfan logins
+		return fmt.Sprintf("justinfan%d", 10000+rand.Intn(90000)), "anonymous", nil
+	}
+
+	if a.tokens == nil {
+		return a.creds.TwitchUser(), a.creds.TwitchPass(), nil
+	}
+
+	tok, err := a.tokens.Token(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	nick := a.creds.TwitchUser()
+	if nick == "" {
+		nick = a.tokens.Login()
+	}
+
+	return nick, "oauth:" + tok, nil
+}
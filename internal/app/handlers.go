@@ -1,29 +1,140 @@
 package app
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/ihrk/microbot/internal/bot"
 	"github.com/ihrk/microbot/internal/bot/actions"
 	"github.com/ihrk/microbot/internal/bot/middlewares"
 	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/creds"
 	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/store"
 )
 
-func appHandler(cfg *config.App) bot.Handler {
+// builder makes handlers of config collecting all errors
+// instead of stopping at the first one.
+type builder struct {
+	cfg   *config.App
+	db    store.DB
+	creds *creds.Creds
+	errs  config.Errors
+}
+
+func (b *builder) fail(path string, err error) {
+	var errs config.Errors
+	if errors.As(err, &errs) {
+		for _, err := range errs {
+			b.fail(path, err)
+		}
+
+		return
+	}
+
+	b.errs = append(b.errs, b.cfg.Locate(path, err))
+}
+
+func (b *builder) serverOptions() bot.Options {
+	w := b.cfg.Workers
+
+	opts := bot.Options{
+		Workers: w.Count,
+		Queue:   w.Queue,
+	}
+
+	if w.Count < 0 {
+		b.fail("workers.count", errors.New("value must not be negative"))
+	}
+
+	if w.Queue < 0 {
+		b.fail("workers.queue", errors.New("value must not be negative"))
+	}
+
+	if w.Timeout != "" {
+		d, err := time.ParseDuration(w.Timeout)
+		if err == nil && d <= 0 {
+			err = errors.New("value must be positive")
+		}
+
+		if err != nil {
+			b.fail("workers.timeout", err)
+		}
+
+		opts.Timeout = d
+	}
+
+	switch w.Lane {
+	case "", "channel":
+		opts.Lane = bot.LaneByChannel
+	case "user":
+		opts.Lane = bot.LaneByUser
+	default:
+		b.fail("workers.lane", fmt.Errorf("unknown lane: %s (expected channel or user)", w.Lane))
+	}
+
+	return opts
+}
+
+func (b *builder) checkRecord() {
+	r := b.cfg.Record
+
+	if r.MaxSize < 0 {
+		b.fail("record.maxSize", errors.New("value must not be negative"))
+	}
+
+	if r.MaxFiles < 0 {
+		b.fail("record.maxFiles", errors.New("value must not be negative"))
+	}
+}
+
+// env returns environment of channel. Anonymous login makes the whole
+// server read-only instead, so offline tools are not affected by it.
+func (b *builder) env(ch *config.Channel) *bot.Env {
+	env := bot.NewEnv(ch.Name, b.db.Namespace(ch.Name), b.creds)
+	env.ReadOnly = ch.ReadOnly
+
+	return env
+}
+
+func (b *builder) appHandler() bot.Handler {
 	r := bot.NewStringRouter(bot.MatchChannel)
 
-	for _, ch := range cfg.Channels {
+	seen := make(map[string]bool)
+
+	for i, ch := range b.cfg.Channels {
+		path := fmt.Sprintf("channels[%d]", i)
+
+		switch {
+		case ch.Name == "":
+			b.fail(path+".name", errors.New("channel name is required"))
+		case seen[ch.Name]:
+			b.fail(path+".name", fmt.Errorf("duplicate channel: %s", ch.Name))
+		}
+
+		seen[ch.Name] = true
+
 		if ch.Chat == nil {
 			continue
 		}
 
-		r.Add(ch.Name, chatHandler(ch.Chat))
+		env := b.env(ch)
+
+		h := b.chatHandler(path+".chat", ch.Chat, env)
+
+		if env.ReadOnly {
+			h = bot.Wrap(h, bot.ReadOnly)
+		}
+
+		r.Add(ch.Name, h)
 	}
 
 	m := bot.NewMux(r)
 
-	if cfg.Debug {
+	if b.cfg.Debug {
 		m = bot.Wrap(m, debug)
 	}
 
@@ -37,35 +148,101 @@ func debug(next bot.Handler) bot.Handler {
 	})
 }
 
-func chatHandler(cfg *config.Chat) bot.Handler {
+func (b *builder) chatHandler(path string, cfg *config.Chat, env *bot.Env) bot.Handler {
+	// bot.MatchCmd strips "!", so such key would never match
+	for i, cmd := range cfg.Commands {
+		if strings.HasPrefix(cmd.Key, "!") {
+			b.fail(fmt.Sprintf("%s.commands[%d].key", path, i),
+				fmt.Errorf("command key must be given without \"!\": %s", cmd.Key))
+		}
+	}
+
 	chat := bot.NewMux(
-		newRouter(cfg.Rewards, bot.MatchReward),
-		newRouter(cfg.Commands, bot.MatchCmd),
+		b.newRouter(path+".rewards", cfg.Rewards, bot.MatchReward, env),
+		b.newRouter(path+".commands", cfg.Commands, bot.MatchCmd, env),
 	)
 
 	r := bot.NewStringRouter(bot.MatchType)
 	r.Add(
 		irc.MsgTypePrivMsg,
 		chat,
-		middlewares.New(cfg.Middlewares),
+		b.middlewares(path+".middlewares", cfg.Middlewares, env),
+	)
+	r.Add(
+		irc.MsgTypeUserNotice,
+		bot.NewMux(b.newRouter(path+".events", cfg.Events, bot.MatchEvent, env)),
 	)
 
 	return bot.NewMux(r)
 }
 
-func newRouter(
+func (b *builder) newRouter(
+	path string,
 	cfgs []*config.Trigger,
 	matcher func(*irc.Msg) (string, bool),
+	env *bot.Env,
 ) bot.Router {
 	r := bot.NewStringRouter(matcher)
 
-	for _, cfg := range cfgs {
-		r.Add(
-			cfg.Key,
-			actions.New(cfg.Action),
-			middlewares.New(cfg.Middlewares),
-		)
+	seen := make(map[string]bool)
+
+	for i, cfg := range cfgs {
+		p := fmt.Sprintf("%s[%d]", path, i)
+
+		switch {
+		case cfg.Key == "":
+			b.fail(p+".key", errors.New("key is required"))
+		case seen[cfg.Key]:
+			b.fail(p+".key", fmt.Errorf("duplicate key: %s", cfg.Key))
+		}
+
+		seen[cfg.Key] = true
+
+		h := b.action(p+".action", cfg.Action, env)
+		mw := b.middlewares(p+".middlewares", cfg.Middlewares, env)
+
+		if h != nil {
+			r.Add(cfg.Key, h, mw)
+		}
 	}
 
 	return r
 }
+
+func (b *builder) action(path string, cfg *config.Feature, env *bot.Env) bot.Handler {
+	if cfg == nil {
+		b.fail(path, errors.New("action is required"))
+		return nil
+	}
+
+	h, err := actions.New(cfg, env)
+	if err != nil {
+		b.fail(path, err)
+		return nil
+	}
+
+	return h
+}
+
+func (b *builder) middlewares(path string, cfgs []*config.Feature, env *bot.Env) bot.Middleware {
+	var mws []bot.Middleware
+
+	for i, cfg := range cfgs {
+		p := fmt.Sprintf("%s[%d]", path, i)
+
+		if cfg == nil {
+			b.fail(p, errors.New("middleware is empty"))
+			continue
+		}
+
+		mw, err := middlewares.New(cfg, env)
+		if err != nil {
+			b.fail(p, err)
+			continue
+		}
+
+		mws = append(mws, mw)
+	}
+
+	return bot.Concat(mws...)
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/creds"
+	"github.com/ihrk/microbot/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+const masked = "******"
+
+// Validate reads config and creds and builds every handler of config
+// without connecting to chat, all found errors are returned. Handlers
+// do not call external APIs when they are built, e.g. summoner of elo
+// is looked up on first use, so validation works offline.
+func Validate(configPath string, credsOpts creds.Options) error {
+	var msgs []string
+
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		msgs = append(msgs, err.Error())
+	}
+
+	cr, err := creds.Load(credsOpts)
+	if err != nil {
+		msgs = append(msgs, err.Error())
+	}
+
+	// handlers can not be built without config and creds
+	if cfg != nil && cr != nil {
+		if _, err = newTokenSource(cr, store.NewMemory()); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+
+		if _, err = loadConfig(cfg, store.NewMemory(), cr); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
+// PrintConfig writes config with definitions resolved and creds
+// that are set, values of creds and secret looking settings are masked.
+func PrintConfig(configPath string, credsOpts creds.Options, out io.Writer) error {
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		return err
+	}
+
+	cr, err := creds.Load(credsOpts)
+	if err != nil {
+		return err
+	}
+
+	// channels have everything of definitions they use
+	cfg.Definitions = config.Definitions{}
+
+	for _, ch := range cfg.Channels {
+		maskChat(ch.Chat)
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+	if err != nil {
+		return err
+	}
+
+	c := make(map[string]string)
+
+	for _, key := range creds.Keys() {
+		v, err := cr.Get(key)
+		if err != nil {
+			continue
+		}
+
+		// login is public, it is shown to help with debugging
+		if key != creds.KeyTwitchUser {
+			v = masked
+		}
+
+		c[key] = v
+	}
+
+	data, err = yaml.Marshal(map[string]interface{}{"creds": c})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "---\n%s", data)
+
+	return err
+}
+
+func maskChat(chat *config.Chat) {
+	if chat == nil {
+		return
+	}
+
+	for _, triggers := range [][]*config.Trigger{chat.Rewards, chat.Commands, chat.Events} {
+		for _, t := range triggers {
+			maskFeatures(t.Action)
+			maskFeatures(t.Middlewares...)
+		}
+	}
+
+	maskFeatures(chat.Middlewares...)
+}
+
+var secretWords = []string{"pass", "secret", "token", "apikey"}
+
+func maskFeatures(fs ...*config.Feature) {
+	for _, f := range fs {
+		if f == nil {
+			continue
+		}
+
+		for k := range f.Settings {
+			name := strings.ToLower(k)
+
+			for _, w := range secretWords {
+				if strings.Contains(name, w) {
+					f.Settings[k] = masked
+					break
+				}
+			}
+		}
+	}
+}
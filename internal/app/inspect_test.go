@@ -0,0 +1,139 @@
+package app
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ihrk/microbot/internal/creds"
+)
+
+const secretConfig = `definitions:
+  actions:
+    hi:
+      type: print
+      settings:
+        text: hi
+        apiToken: secret-value
+channels:
+  - name: first
+    chat:
+      commands:
+        - key: hi
+          action:
+            use: hi
+`
+
+func TestPrintConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := os.WriteFile(path, []byte(secretConfig), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"MICROBOT_TWITCHUSER": "bot",
+		"MICROBOT_TWITCHPASS": "oauth:token",
+	}
+
+	credsOpts := creds.Options{
+		Getenv: func(key string) string { return env[key] },
+	}
+
+	var out strings.Builder
+
+	err = PrintConfig(path, credsOpts, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEq(t, `store: ./store.log
+channels:
+    - name: first
+      chat:
+        commands:
+            - key: hi
+              action:
+                type: print
+                settings:
+                    apiToken: '******'
+                    text: hi
+---
+creds:
+    twitchpass: '******'
+    twitchuser: bot
+`, out.String())
+
+	// settings are checked as well as creds
+	err = Validate(path, credsOpts)
+	if err == nil || !strings.Contains(err.Error(), "apiToken: unknown setting") {
+		t.Errorf("expected unknown setting error, got: %v", err)
+	}
+}
+
+const eloConfig = `channels:
+  - name: first
+    chat:
+      commands:
+        - key: rank
+          action:
+            type: elo
+            settings:
+              region: euw
+              summonerName: someone
+`
+
+func TestValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := os.WriteFile(path, []byte(eloConfig), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"MICROBOT_TWITCHUSER": "bot",
+		"MICROBOT_TWITCHPASS": "oauth:token",
+		"MICROBOT_RIOTAPIKEY": "key",
+	}
+
+	credsOpts := creds.Options{
+		Getenv: func(key string) string { return env[key] },
+	}
+
+	// elo is built without requests to riot api
+	assertEq(t, nil, Validate(path, credsOpts))
+
+	// typos of structural keys make config invalid
+	err = os.WriteFile(path, []byte(strings.Replace(eloConfig, "commands:", "comands:", 1)), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Validate(path, credsOpts)
+	if err == nil || !strings.Contains(err.Error(), "channels[0].chat.comands: unknown key") {
+		t.Errorf("expected unknown key error, got: %v", err)
+	}
+
+	// empty items are errors instead of panic
+	err = os.WriteFile(path, []byte(eloConfig+"      middlewares:\n        -\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = PrintConfig(path, credsOpts, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "empty list item") {
+		t.Errorf("expected empty item error, got: %v", err)
+	}
+
+	// errors of config and creds are reported together
+	credsOpts.File = filepath.Join(t.TempDir(), "creds.yml")
+
+	err = Validate(filepath.Join(t.TempDir(), "config.yaml"), credsOpts)
+	if err == nil || strings.Count(err.Error(), "no such file") != 2 {
+		t.Errorf("expected errors of config and creds, got: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/irc"
+)
+
+const watchInterval = 2 * time.Second
+
+// watch reloads config when its file is changed
+// or process receives SIGHUP.
+func (a *app) watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	last := modTime(a.configPath)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Println("SIGHUP received, reloading config")
+		case <-ticker.C:
+			t := modTime(a.configPath)
+			if t.Equal(last) {
+				continue
+			}
+
+			last = t
+
+			log.Println("config file changed, reloading config")
+		}
+
+		a.reload(ctx)
+	}
+}
+
+func modTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return fi.ModTime()
+}
+
+// reload builds handlers of new config and swaps them with current ones,
+// current handlers are kept if config has errors.
+func (a *app) reload(ctx context.Context) {
+	cfg, err := config.Read(a.configPath)
+	if err != nil {
+		log.Printf("config reload failed, keeping current config: %v\n", err)
+		return
+	}
+
+	if cfg.Store != a.storePath {
+		log.Printf("store path can not be changed without restart, using %s\n",
+			a.storePath)
+	}
+
+	if cfg.Workers != a.workers {
+		log.Println("workers settings can not be changed without restart, using previous ones")
+	}
+
+	if cfg.Record != a.record {
+		log.Println("record settings can not be changed without restart, using previous ones")
+	}
+
+	s, err := loadConfig(cfg, a.db, a.creds)
+	if err != nil {
+		log.Printf("config reload failed, keeping current config:\n%v\n", err)
+		return
+	}
+
+	a.apply(ctx, s)
+
+	log.Println("config reloaded")
+}
+
+// apply makes s current: handler of server is replaced,
+// timers are restarted and channels are joined or parted.
+func (a *app) apply(ctx context.Context, s *setup) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if a.stopTimers != nil {
+		a.stopTimers()
+	}
+
+	var timersCtx context.Context
+	timersCtx, a.stopTimers = context.WithCancel(ctx)
+
+	s.sched.run(timersCtx, a.srv)
+
+	var old []string
+	if a.setup != nil {
+		old = a.setup.channels
+	}
+
+	a.setup = s
+	a.srv.SetHandler(s.h)
+
+	if a.client == nil {
+		return
+	}
+
+	for _, channel := range subtract(old, s.channels) {
+		if err := a.client.Part(channel); err != nil {
+			log.Printf("part %s error: %v\n", channel, err)
+		}
+	}
+
+	for _, channel := range subtract(s.channels, old) {
+		if err := a.client.Join(channel); err != nil {
+			log.Printf("join %s error: %v\n", channel, err)
+		}
+	}
+}
+
+// joinAll joins channels of current config and makes c
+// a target of following joins and parts.
+func (a *app) joinAll(c *irc.Client) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	for _, channel := range a.setup.channels {
+		err := c.Join(channel)
+		if err != nil {
+			return err
+		}
+	}
+
+	a.client = c
+
+	return nil
+}
+
+func (a *app) setClient(c *irc.Client) {
+	a.m.Lock()
+	a.client = c
+	a.m.Unlock()
+}
+
+// subtract returns elements of x missing in y.
+func subtract(x, y []string) []string {
+	set := make(map[string]bool, len(y))
+	for _, e := range y {
+		set[e] = true
+	}
+
+	var diff []string
+
+	for _, e := range x {
+		if !set[e] {
+			diff = append(diff, e)
+		}
+	}
+
+	return diff
+}
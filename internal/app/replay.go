@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ihrk/microbot/internal/creds"
+	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/record"
+)
+
+const replayTimeFormat = "2006-01-02 15:04:05"
+
+// Replay passes recorded lines of files to handlers of config and
+// writes responses to out. Lines are delayed by intervals between
+// them divided by speed, zero speed replays without delays.
+func Replay(
+	ctx context.Context,
+	configPath string,
+	credsOpts creds.Options,
+	files []string,
+	speed float64,
+	out io.Writer,
+) error {
+	if speed < 0 {
+		return errors.New("speed must not be negative")
+	}
+
+	s, err := loadOffline(configPath, credsOpts)
+	if err != nil {
+		return err
+	}
+
+	var last time.Time
+
+	for _, path := range files {
+		err = replayFile(ctx, path, func(e record.Entry) error {
+			if speed > 0 && !last.IsZero() && e.Time.After(last) {
+				err := sleep(ctx, time.Duration(float64(e.Time.Sub(last))/speed))
+				if err != nil {
+					return err
+				}
+			}
+
+			last = e.Time
+
+			msg := irc.ParseMsg(e.Line)
+
+			for _, resp := range serveMsg(ctx, s.h, msg) {
+				fmt.Fprintf(out, "%s ", e.Time.Local().Format(replayTimeFormat))
+				printResp(out, resp, msg)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func replayFile(ctx context.Context, path string, fn func(record.Entry) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	r := record.NewReader(f)
+
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if err = fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
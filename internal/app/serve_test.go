@@ -0,0 +1,232 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihrk/microbot/internal/bot"
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/creds"
+	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/irc/irctest"
+	"github.com/ihrk/microbot/internal/store"
+)
+
+const filterConfig = `channels:
+  - name: first
+    chat:
+      middlewares:
+        - type: filter
+          settings:
+            type: blockLinks
+            penalty: deleteMsg
+            allowMod: true
+            reply: links are not allowed
+`
+
+const (
+	expectTimeout = 5 * time.Second
+	// silenceTimeout is how long nothing must be sent
+	silenceTimeout = 300 * time.Millisecond
+)
+
+var loginEnv = map[string]string{
+	"MICROBOT_TWITCHUSER": "bot",
+	"MICROBOT_TWITCHPASS": "oauth:token",
+}
+
+// testApp is app connected to fake chat server.
+type testApp struct {
+	fake    *irctest.Server
+	cancel  context.CancelFunc
+	stopped chan error
+}
+
+// startApp runs app with config text and creds from env
+// against fake server, it is stopped when test ends.
+func startApp(t *testing.T, text string, env map[string]string) *testApp {
+	t.Helper()
+
+	fake := irctest.NewServer()
+	t.Cleanup(fake.Close)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := os.WriteFile(path, []byte(text), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := creds.Load(creds.Options{
+		Getenv: func(key string) string { return env[key] },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := store.NewMemory()
+
+	s, err := loadConfig(cfg, db, cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := s.opts
+	opts.ReadOnly = anonymous(cr)
+
+	a := &app{
+		configPath: path,
+		chatURL:    fake.URL,
+		storePath:  cfg.Store,
+		db:         db,
+		creds:      cr,
+		srv:        bot.NewServer(s.h, opts),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	a.apply(ctx, s)
+
+	ta := &testApp{
+		fake:    fake,
+		cancel:  cancel,
+		stopped: make(chan error, 1),
+	}
+
+	go func() {
+		ta.stopped <- a.run(ctx)
+	}()
+
+	return ta
+}
+
+func (ta *testApp) expect(t *testing.T, match func(*irc.Msg) bool) *irc.Msg {
+	t.Helper()
+
+	msg, err := ta.fake.Expect(expectTimeout, match)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return msg
+}
+
+func isType(tp string) func(*irc.Msg) bool {
+	return func(msg *irc.Msg) bool {
+		return msg.Type == tp
+	}
+}
+
+// join waits until app joins channel and reads chat.
+func (ta *testApp) join(t *testing.T, channel string) *irc.Msg {
+	t.Helper()
+
+	login := ta.expect(t, isType(irc.MsgTypeNick))
+
+	join := ta.expect(t, isType(irc.MsgTypeJoin))
+	assertEq(t, []string{"#" + channel}, join.Params)
+
+	if err := ta.fake.Ping(expectTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	return login
+}
+
+var viewerTags = map[string]string{"id": "2", "badges": "", "mod": "0"}
+
+func TestServeFilter(t *testing.T) {
+	ta := startApp(t, filterConfig, loginEnv)
+
+	login := ta.join(t, "first")
+	assertEq(t, []string{"bot"}, login.Params)
+
+	modTags := map[string]string{"id": "1", "badges": "moderator/1", "mod": "1"}
+	if err := ta.fake.PrivMsg("first", "mod", "see https://example.com", modTags); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ta.fake.PrivMsg("first", "viewer", "see https://example.com", viewerTags); err != nil {
+		t.Fatal(err)
+	}
+
+	// link of mod is not deleted, so the first response is to viewer
+	del := ta.expect(t, isType(irc.MsgTypePrivMsg))
+	assertEq(t, "/delete 2", del.Text)
+
+	reply := ta.expect(t, isType(irc.MsgTypePrivMsg))
+	assertEq(t, "links are not allowed", reply.Text)
+	assertEq(t, "2", reply.Tags["reply-parent-msg-id"])
+
+	ta.cancel()
+
+	select {
+	case err := <-ta.stopped:
+		assertEq(t, context.Canceled, err)
+	case <-time.After(expectTimeout):
+		t.Fatal("bot is not stopped")
+	}
+
+	part := ta.expect(t, isType(irc.MsgTypeLeave))
+	assertEq(t, []string{"#first"}, part.Params)
+
+	ta.expect(t, isType(irc.MsgTypeQuit))
+}
+
+func TestServeAnonymous(t *testing.T) {
+	ta := startApp(t, filterConfig, nil)
+
+	login := ta.join(t, "first")
+
+	if nick := login.Params[0]; !strings.HasPrefix(nick, "justinfan") {
+		t.Errorf("expected anonymous login, got: %s", nick)
+	}
+
+	if err := ta.fake.PrivMsg("first", "viewer", "see https://example.com", viewerTags); err != nil {
+		t.Fatal(err)
+	}
+
+	// filter handles message, but nothing is sent anonymously
+	msg, err := ta.fake.Expect(silenceTimeout, isType(irc.MsgTypePrivMsg))
+	if err == nil {
+		t.Errorf("unexpected message is sent: %s", msg.Raw)
+	}
+}
+
+const eventsConfig = `channels:
+  - name: first
+    chat:
+      events:
+        - key: subgift
+          action:
+            type: print
+            settings:
+              text: "Thanks {name} for gifting tier {tier} sub to {recipient}!"
+`
+
+func TestServeEvent(t *testing.T) {
+	ta := startApp(t, eventsConfig, loginEnv)
+
+	ta.join(t, "first")
+
+	err := ta.fake.Send("@badges=;display-name=Gifter;id=3;login=gifter;msg-id=subgift;" +
+		"msg-param-recipient-display-name=Lucky;msg-param-recipient-user-name=lucky;" +
+		"msg-param-sub-plan=1000;room-id=1 :tmi.twitch.tv USERNOTICE #first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply := ta.expect(t, isType(irc.MsgTypePrivMsg))
+	assertEq(t, []string{"#first"}, reply.Params)
+	assertEq(t, "Thanks Gifter for gifting tier 1 sub to Lucky!", reply.Text)
+}
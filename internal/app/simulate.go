@@ -0,0 +1,243 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/ihrk/microbot/internal/bot"
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/creds"
+	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/store"
+)
+
+const defaultSimUser = "viewer"
+
+// Simulate builds handlers of config and passes them chat lines read
+// from in, responses are written to out, responses that would not be
+// sent because channel is read-only are marked. Store is kept in memory,
+// so persistent state is not changed. Line format is
+//
+//	[#channel] [@user] [+role...] [key=value...] [--] text
+//
+// where role is broadcaster, mod, vip or sub, reward and bits keys
+// are shorthands for custom-reward-id and bits tags, other keys
+// are set as tags as is, e.g. badges=vip/1. Channel is kept until
+// it is changed, the first channel of config is used by default.
+func Simulate(ctx context.Context, configPath string, credsOpts creds.Options, in io.Reader, out io.Writer) error {
+	s, err := loadOffline(configPath, credsOpts)
+	if err != nil {
+		return err
+	}
+
+	if len(s.channels) == 0 {
+		return errors.New("config has no channels")
+	}
+
+	sim := simulator{
+		h:       s.h,
+		channel: strings.TrimPrefix(s.channels[0], "#"),
+	}
+
+	sc := bufio.NewScanner(in)
+
+	for sc.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		msg, err := sim.parse(line)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+
+		for _, resp := range serveMsg(ctx, sim.h, msg) {
+			printResp(out, resp, msg)
+		}
+	}
+
+	return sc.Err()
+}
+
+// loadOffline builds handlers of config with store kept in memory.
+func loadOffline(configPath string, credsOpts creds.Options) (*setup, error) {
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cr, err := creds.Load(credsOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadConfig(cfg, store.NewMemory(), cr)
+}
+
+type simulator struct {
+	h       bot.Handler
+	channel string
+	seq     int // id of the last message
+}
+
+var simRoles = map[string]string{
+	"broadcaster": irc.BadgeBroadcaster + "/1",
+	"mod":         irc.BadgeModerator + "/1",
+	"vip":         irc.BadgeVIP + "/1",
+	"sub":         irc.BadgeSubscriber + "/1",
+}
+
+var simTags = map[string]string{
+	"reward": "custom-reward-id",
+	"bits":   "bits",
+}
+
+func (sim *simulator) parse(line string) (*irc.Msg, error) {
+	user := defaultSimUser
+	tags := make(map[string]string)
+
+	var badges []string
+
+	fields := strings.Fields(line)
+
+	i := 0
+
+loop:
+	for ; i < len(fields); i++ {
+		f := fields[i]
+
+		switch {
+		case f == "--":
+			i++
+			break loop
+		case strings.HasPrefix(f, "#") && len(f) > 1:
+			sim.channel = strings.ToLower(f[1:])
+		case strings.HasPrefix(f, "@") && len(f) > 1:
+			user = strings.ToLower(f[1:])
+		case strings.HasPrefix(f, "+") && len(f) > 1:
+			badge, ok := simRoles[f[1:]]
+			if !ok {
+				return nil, fmt.Errorf("unknown role: %s", f[1:])
+			}
+
+			badges = append(badges, badge)
+		case strings.Contains(f, "="):
+			key, value := splitPair(f)
+
+			if tag, ok := simTags[key]; ok {
+				key = tag
+			}
+
+			if key == "bits" {
+				if _, err := strconv.Atoi(value); err != nil {
+					return nil, fmt.Errorf("bits must be a number: %s", value)
+				}
+			}
+
+			tags[key] = value
+		default:
+			break loop
+		}
+	}
+
+	text := strings.Join(fields[i:], " ")
+	if text == "" && tags["custom-reward-id"] == "" {
+		return nil, errors.New("message text is empty")
+	}
+
+	if len(badges) > 0 {
+		if tags["badges"] != "" {
+			badges = append(badges, tags["badges"])
+		}
+
+		tags["badges"] = strings.Join(badges, ",")
+	}
+
+	if _, ok := tags["mod"]; !ok && strings.Contains(tags["badges"], irc.BadgeModerator+"/") {
+		tags["mod"] = "1"
+	}
+
+	sim.seq++
+
+	tags["id"] = strconv.Itoa(sim.seq)
+	tags["display-name"] = user
+
+	raw := &irc.Msg{
+		Tags: tags,
+		Prefix: irc.Prefix{
+			Nick: user,
+			User: user,
+			Host: user + ".tmi.twitch.tv",
+		},
+		Type:   irc.MsgTypePrivMsg,
+		Params: []string{"#" + sim.channel},
+		Text:   text,
+	}
+
+	// message is encoded and parsed back to look like received one
+	return irc.ParseMsg(raw.Encode()), nil
+}
+
+func splitPair(s string) (string, string) {
+	i := strings.IndexByte(s, '=')
+	return s[:i], s[i+1:]
+}
+
+// serveMsg runs handler and collects its responses.
+func serveMsg(ctx context.Context, h bot.Handler, msg *irc.Msg) []bot.Response {
+	var resps []bot.Response
+
+	respCh := make(chan bot.Response)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("handler panic: %v\n", r)
+			}
+		}()
+
+		h.Serve(bot.NewSender(ctx, msg, respCh))
+	}()
+
+	for {
+		select {
+		case resp := <-respCh:
+			resps = append(resps, resp)
+		case <-done:
+			return resps
+		}
+	}
+}
+
+func printResp(w io.Writer, resp bot.Response, msg *irc.Msg) {
+	var notes []string
+
+	if resp.ReadOnly {
+		notes = append(notes, "read-only")
+	}
+
+	if resp.ParentMsgID != "" {
+		notes = append(notes, "reply to "+msg.User)
+	}
+
+	if len(notes) == 0 {
+		fmt.Fprintf(w, "#%s: %s\n", resp.Channel, resp.Text)
+	} else {
+		fmt.Fprintf(w, "#%s (%s): %s\n", resp.Channel, strings.Join(notes, ", "), resp.Text)
+	}
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihrk/microbot/internal/creds"
+	"github.com/ihrk/microbot/internal/record"
+)
+
+const simConfig = `channels:
+  - name: first
+    chat:
+      middlewares:
+        - type: filter
+          settings:
+            type: blockLinks
+            penalty: deleteMsg
+            allowMod: true
+      commands:
+        - key: hello
+          action:
+            type: print
+            settings:
+              text: hello, {user}
+      rewards:
+        - key: reward-id
+          action:
+            type: print
+            settings:
+              text: thanks for {bits} bits
+  - name: second
+    chat:
+      commands:
+        - key: hello
+          action:
+            type: print
+            settings:
+              text: hi from second
+  - name: third
+    readOnly: true
+    chat:
+      commands:
+        - key: hello
+          action:
+            type: print
+            settings:
+              text: hi from third
+`
+
+// noCreds have no login, offline tools do not make
+// channels read-only because of it.
+var noCreds = creds.Options{
+	Getenv: func(string) string { return "" },
+}
+
+func TestSimulate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := os.WriteFile(path, []byte(simConfig), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := strings.NewReader(`!hello
+@someone see https://example.com
++mod see https://example.com
+reward=reward-id bits=100 -- hi
++king hi
+#second !hello
+#third !hello
+`)
+
+	var out strings.Builder
+
+	err = Simulate(context.Background(), path, noCreds, in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEq(t, `#first (reply to viewer): hello, viewer
+#first: /delete 2
+#first (reply to viewer): thanks for 100 bits
+error: unknown role: king
+#second (reply to viewer): hi from second
+#third (read-only, reply to viewer): hi from third
+`, out.String())
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "config.yaml")
+
+	err := os.WriteFile(path, []byte(simConfig), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recPath := filepath.Join(dir, "chat.log")
+
+	w, err := record.Open(recPath, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2022, 1, 2, 3, 4, 5, 0, time.Local)
+
+	for i, line := range []string{
+		"PING :tmi.twitch.tv",
+		"@badges=;id=1 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #first :see https://example.com",
+		"@badges=moderator/1;id=2 :mod!mod@mod.tmi.twitch.tv PRIVMSG #first :see https://example.com",
+		"@badges=;id=3 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #second :!hello",
+	} {
+		err = w.Write(record.Entry{Time: at.Add(time.Duration(i) * time.Hour), Line: line})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.Close()
+
+	var out strings.Builder
+
+	err = Replay(context.Background(), path, noCreds, []string{recPath}, 0, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEq(t, `2022-01-02 04:04:05 #first: /delete 1
+2022-01-02 06:04:05 #second (reply to viewer): hi from second
+`, out.String())
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/ihrk/microbot/internal/bot"
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/cron"
+	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/tmpl"
+)
+
+type timer struct {
+	channel  string
+	next     func(time.Time) time.Time
+	minLines int64
+	msgs     []*tmpl.Template
+	env      *bot.Env
+
+	lines int64 // chat lines since last post, accessed atomically
+}
+
+// scheduler posts messages of timers to channels
+// independently of chat activity.
+type scheduler struct {
+	timers    []*timer
+	byChannel map[string][]*timer
+}
+
+func (b *builder) scheduler() *scheduler {
+	s := &scheduler{
+		byChannel: make(map[string][]*timer),
+	}
+
+	for i, ch := range b.cfg.Channels {
+		if ch.Chat == nil {
+			continue
+		}
+
+		env := b.env(ch)
+
+		for j, timerCfg := range ch.Chat.Timers {
+			t, err := newTimer(timerCfg, env)
+			if err != nil {
+				b.fail(fmt.Sprintf("channels[%d].chat.timers[%d]", i, j), err)
+				continue
+			}
+
+			s.timers = append(s.timers, t)
+			s.byChannel[ch.Name] = append(s.byChannel[ch.Name], t)
+		}
+	}
+
+	return s
+}
+
+func newTimer(cfg *config.Timer, env *bot.Env) (*timer, error) {
+	t := timer{
+		channel:  env.Channel,
+		minLines: int64(cfg.MinLines),
+		env:      env,
+	}
+
+	switch {
+	case cfg.Interval != "" && cfg.Cron != "":
+		return nil, errors.New("timer can not have both interval and cron")
+	case cfg.Interval != "":
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil {
+			return nil, config.Errorf("interval", "%w", err)
+		}
+
+		if d <= 0 {
+			return nil, config.Errorf("interval", "timer interval must be positive: %v", d)
+		}
+
+		t.next = func(now time.Time) time.Time {
+			return now.Add(d)
+		}
+	case cfg.Cron != "":
+		sched, err := cron.Parse(cfg.Cron)
+		if err != nil {
+			return nil, config.Errorf("cron", "%w", err)
+		}
+
+		t.next = sched.Next
+	default:
+		return nil, errors.New("timer requires either interval or cron")
+	}
+
+	if len(cfg.Messages) == 0 {
+		return nil, config.Errorf("messages", "timer requires at least one message")
+	}
+
+	for i, text := range cfg.Messages {
+		msg, err := tmpl.Parse(text)
+		if err != nil {
+			return nil, config.Errorf(fmt.Sprintf("messages[%d]", i), "%w", err)
+		}
+
+		t.msgs = append(t.msgs, msg)
+	}
+
+	return &t, nil
+}
+
+// count is a middleware that counts chat lines for timers.
+func (s *scheduler) count(next bot.Handler) bot.Handler {
+	return bot.HandlerFunc(func(snd *bot.Sender) {
+		if snd.Msg.Type == irc.MsgTypePrivMsg {
+			for _, t := range s.byChannel[snd.Msg.Channel] {
+				atomic.AddInt64(&t.lines, 1)
+			}
+		}
+
+		next.Serve(snd)
+	})
+}
+
+func (s *scheduler) run(ctx context.Context, srv *bot.Server) {
+	for _, t := range s.timers {
+		go t.run(ctx, srv.Send)
+	}
+}
+
+func (t *timer) run(ctx context.Context, send func(ctx context.Context, channel, text string) error) {
+	var i int
+
+	for at := t.next(time.Now()); !at.IsZero(); at = t.next(time.Now()) {
+		wait := time.NewTimer(time.Until(at))
+
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return
+		case <-wait.C:
+		}
+
+		if atomic.LoadInt64(&t.lines) < t.minLines {
+			continue
+		}
+
+		atomic.StoreInt64(&t.lines, 0)
+
+		msg := &irc.Msg{Channel: t.channel}
+		text := t.msgs[i].Execute(t.env.Data(msg))
+
+		if t.env.ReadOnly {
+			log.Printf("read-only #%s, timer message not sent: %s\n", t.channel, text)
+		} else if err := send(ctx, t.channel, text); err != nil {
+			// timers are stopped by reload while send waits for connection
+			return
+		}
+
+		i = (i + 1) % len(t.msgs)
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ihrk/microbot/internal/bot"
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/store"
+)
+
+func TestTimerMinLinesAndRotation(t *testing.T) {
+	env := bot.NewEnv("first", store.NewMemory().Namespace("first"), nil)
+
+	tm, err := newTimer(&config.Timer{
+		Interval: "5ms",
+		MinLines: 2,
+		Messages: []string{"one", "two"},
+	}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &scheduler{
+		timers:    []*timer{tm},
+		byChannel: map[string][]*timer{"first": {tm}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sent := make(chan string, 1)
+
+	go tm.run(ctx, func(_ context.Context, channel, text string) error {
+		assertEq(t, "first", channel)
+		sent <- text
+
+		return nil
+	})
+
+	h := s.count(bot.HandlerFunc(func(*bot.Sender) {}))
+
+	chat := func(lines int) {
+		for i := 0; i < lines; i++ {
+			msg := &irc.Msg{Type: irc.MsgTypePrivMsg, Channel: "first"}
+			h.Serve(bot.NewSender(ctx, msg, nil))
+		}
+	}
+
+	expect := func(text string) {
+		t.Helper()
+
+		select {
+		case actual := <-sent:
+			assertEq(t, text, actual)
+		case <-time.After(time.Second):
+			t.Fatalf("timer message %q is not sent", text)
+		}
+	}
+
+	expectNone := func() {
+		t.Helper()
+
+		select {
+		case actual := <-sent:
+			t.Errorf("unexpected timer message: %s", actual)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// post is skipped until chat has enough lines
+	expectNone()
+	chat(1)
+	expectNone()
+	chat(1)
+	expect("one")
+
+	// lines are counted from the last post, messages rotate
+	expectNone()
+	chat(2)
+	expect("two")
+	chat(2)
+	expect("one")
+}
+
+func TestTimerStopsWhileSendIsBlocked(t *testing.T) {
+	env := bot.NewEnv("first", store.NewMemory().Namespace("first"), nil)
+
+	tm, err := newTimer(&config.Timer{
+		Interval: "1ms",
+		Messages: []string{"hi"},
+	}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// responses of server are not read while it is disconnected,
+	// so send blocks when its queue is full
+	srv := bot.NewServer(bot.HandlerFunc(func(*bot.Sender) {}), bot.Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan struct{})
+
+	go func() {
+		tm.run(ctx, srv.Send)
+		close(stopped)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("timer is not stopped")
+	}
+}
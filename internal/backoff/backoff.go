@@ -1,23 +1,145 @@
 package backoff
 
-import "time"
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
 
-func RunWithRetry(retryLim int, retryBreak time.Duration, f func() error) error {
-	var (
-		err  error
-		wait time.Duration
-	)
+// ErrExhausted is returned by Wait when all retries are used.
+var ErrExhausted = errors.New("retries exhausted")
 
-	for i := 0; i < retryLim; i++ {
-		err = f()
-		if err == nil {
-			break
+// Policy describes delays between attempts: first delay is Min,
+// every next one is Multiplier times longer up to Max. Each delay
+// is randomly changed by up to Jitter fraction of it.
+type Policy struct {
+	Min        time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+
+	// Retries limits number of retries, zero means unlimited.
+	Retries int
+
+	// ResetAfter is duration after Succeeded call when
+	// delays start from Min again.
+	ResetAfter time.Duration
+}
+
+// Backoff tracks attempts of a single operation, it is
+// not safe for concurrent use.
+type Backoff struct {
+	p       Policy
+	attempt int
+	since   time.Time // time of last success
+	rnd     *rand.Rand
+}
+
+func New(p Policy) *Backoff {
+	return &Backoff{
+		p:   p,
+		rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Next returns delay before next attempt,
+// false is returned if retries are exhausted.
+func (b *Backoff) Next() (time.Duration, bool) {
+	if !b.since.IsZero() {
+		if time.Since(b.since) >= b.p.ResetAfter {
+			b.attempt = 0
 		}
 
-		time.Sleep(wait)
+		b.since = time.Time{}
+	}
+
+	if b.p.Retries > 0 && b.attempt >= b.p.Retries {
+		return 0, false
+	}
+
+	d := float64(b.p.Min) * math.Pow(b.p.Multiplier, float64(b.attempt))
+	if max := float64(b.p.Max); b.p.Max > 0 && d > max {
+		d = max
+	}
+
+	d *= 1 + b.p.Jitter*(2*b.rnd.Float64()-1)
+
+	b.attempt++
+
+	return time.Duration(d), true
+}
+
+// Wait sleeps for the next delay, it returns early with
+// error of ctx if ctx is done.
+func (b *Backoff) Wait(ctx context.Context) error {
+	d, ok := b.Next()
+	if !ok {
+		return ErrExhausted
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
 
-		wait = retryBreak << i
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
+}
+
+// Succeeded marks start of successful period, delays are reset
+// if it lasts for ResetAfter before the next failure.
+func (b *Backoff) Succeeded() {
+	b.since = time.Now()
+}
+
+func (b *Backoff) Reset() {
+	b.attempt = 0
+	b.since = time.Time{}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
 
-	return err
+// Permanent wraps err to stop Retry.
+func Permanent(err error) error {
+	return &permanentError{err}
+}
+
+// Retry calls f until it succeeds, returns permanent error or retries
+// of p are exhausted, last error of f is returned then.
+func Retry(ctx context.Context, p Policy, f func() error) error {
+	b := New(p)
+
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		var pe *permanentError
+		if errors.As(err, &pe) {
+			return pe.err
+		}
+
+		if werr := b.Wait(ctx); werr != nil {
+			if errors.Is(werr, ErrExhausted) {
+				return err
+			}
+
+			return werr
+		}
+	}
 }
@@ -0,0 +1,102 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func assertEq(t *testing.T, expected, actual interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nexpected: '%v',\nactual: '%v'", expected, actual)
+	}
+}
+
+func TestNext(t *testing.T) {
+	b := New(Policy{
+		Min:        time.Second,
+		Max:        5 * time.Second,
+		Multiplier: 2,
+		Retries:    5,
+	})
+
+	var delays []time.Duration
+
+	for {
+		d, ok := b.Next()
+		if !ok {
+			break
+		}
+
+		delays = append(delays, d)
+	}
+
+	assertEq(t, []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+	}, delays)
+
+	// short successful period does not reset delays
+	b = New(Policy{Min: time.Second, Multiplier: 2, ResetAfter: time.Hour})
+	b.Next()
+	b.Succeeded()
+
+	d, _ := b.Next()
+	assertEq(t, 2*time.Second, d)
+
+	b = New(Policy{Min: time.Second, Multiplier: 2})
+	b.Next()
+	b.Succeeded()
+
+	d, _ = b.Next()
+	assertEq(t, time.Second, d)
+}
+
+func TestJitter(t *testing.T) {
+	b := New(Policy{Min: time.Second, Multiplier: 1, Jitter: 0.5})
+
+	for i := 0; i < 100; i++ {
+		d, _ := b.Next()
+		if d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("delay out of jitter range: %v", d)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	p := Policy{Min: time.Millisecond, Multiplier: 1, Retries: 2}
+	fail := errors.New("fail")
+
+	var calls int
+
+	err := Retry(context.Background(), p, func() error {
+		calls++
+		return fail
+	})
+	assertEq(t, fail, err)
+	assertEq(t, 3, calls)
+
+	calls = 0
+
+	err = Retry(context.Background(), p, func() error {
+		calls++
+		return Permanent(fail)
+	})
+	assertEq(t, fail, err)
+	assertEq(t, 1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = Retry(ctx, Policy{Min: time.Hour}, func() error {
+		return fail
+	})
+	assertEq(t, context.Canceled, err)
+}
@@ -0,0 +1,48 @@
+package backoff
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTP is policy of requests to external APIs.
+var HTTP = Policy{
+	Min:        250 * time.Millisecond,
+	Max:        4 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+	Retries:    3,
+}
+
+// Get sends GET request retrying it on network errors, 429 and
+// 5xx statuses. Response of the last attempt is returned.
+func Get(ctx context.Context, p Policy, url string) (*http.Response, error) {
+	var resp *http.Response
+
+	err := Retry(ctx, p, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return Permanent(err)
+		}
+
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode >= http.StatusInternalServerError {
+			resp.Body.Close()
+			return fmt.Errorf("request failed with status: %s", resp.Status)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,98 @@
+package actions
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/ihrk/microbot/internal/bot"
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/store"
+	"github.com/ihrk/microbot/internal/tmpl"
+)
+
+const (
+	counterOpShow  = "show"
+	counterOpInc   = "inc"
+	counterOpDec   = "dec"
+	counterOpSet   = "set"
+	counterOpReset = "reset"
+)
+
+type counterSettings struct {
+	bot.Roles
+
+	Name  string `config:"name,required"`
+	Op    string `config:"op" default:"show" oneof:"show inc dec set reset"`
+	Step  int64  `config:"step" default:"1"`
+	Reply string `config:"reply" default:"{counter}: {value}"`
+}
+
+// Counter shows or changes named counter of the channel, every operation
+// except show is allowed only for roles from settings. Value for set
+// is taken from the first argument of the command.
+func Counter(cfg config.Settings, env *bot.Env) (bot.Handler, error) {
+	var opts counterSettings
+
+	err := cfg.Decode(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	name, op, step, allow := opts.Name, opts.Op, opts.Step, opts.Roles
+
+	reply, err := tmpl.Parse(opts.Reply, "counter", "value")
+	if err != nil {
+		return nil, config.Errorf("settings.reply", "%w", err)
+	}
+
+	return bot.HandlerFunc(func(s *bot.Sender) {
+		if op != counterOpShow && !allow.Allow(s.Msg) {
+			return
+		}
+
+		var (
+			value int64
+			err   error
+		)
+
+		switch op {
+		case counterOpShow:
+			value = store.Counter(env.Store, name)
+		case counterOpInc:
+			value, err = store.AddCounter(env.Store, name, step)
+		case counterOpDec:
+			value, err = store.AddCounter(env.Store, name, -step)
+		case counterOpSet:
+			words := strings.Fields(s.Msg.Text)
+			if len(words) < 2 {
+				s.Reply("Error: value is required")
+				return
+			}
+
+			value, err = strconv.ParseInt(words[1], 10, 64)
+			if err != nil {
+				s.Reply("Error: value must be a number")
+				return
+			}
+
+			err = store.SetCounter(env.Store, name, value)
+		case counterOpReset:
+			err = store.SetCounter(env.Store, name, 0)
+		}
+
+		if err != nil {
+			log.Printf("counter update error: %v\n", err)
+			s.Reply("Error: try again later")
+			return
+		}
+
+		data := env.Data(s.Msg)
+		data.Vars = map[string]string{
+			"counter": name,
+			"value":   strconv.FormatInt(value, 10),
+		}
+
+		s.Reply(reply.Execute(data))
+	}), nil
+}
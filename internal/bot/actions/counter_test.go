@@ -0,0 +1,99 @@
+package actions
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ihrk/microbot/internal/bot"
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/store"
+)
+
+func assertEq(t *testing.T, expected, actual interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nexpected: '%v',\nactual: '%v'", expected, actual)
+	}
+}
+
+func newTestEnv() *bot.Env {
+	return bot.NewEnv("first", store.NewMemory().Namespace("first"), nil)
+}
+
+func newTestHandler(t *testing.T, action func(config.Settings, *bot.Env) (bot.Handler, error), env *bot.Env, settings config.Settings) bot.Handler {
+	t.Helper()
+
+	h, err := action(settings, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+// serve passes message of user to h and returns texts of responses,
+// broadcaster is allowed to use every action.
+func serve(h bot.Handler, user, text string, broadcaster bool) []string {
+	badges := ""
+	if broadcaster {
+		badges = irc.BadgeBroadcaster + "/1"
+	}
+
+	msg := irc.ParseMsg((&irc.Msg{
+		Tags: map[string]string{"id": "1", "badges": badges},
+		Prefix: irc.Prefix{
+			Nick: user,
+			User: user,
+			Host: user + ".tmi.twitch.tv",
+		},
+		Type:   irc.MsgTypePrivMsg,
+		Params: []string{"#first"},
+		Text:   text,
+	}).Encode())
+
+	respCh := make(chan bot.Response, 16)
+	h.Serve(bot.NewSender(context.Background(), msg, respCh))
+	close(respCh)
+
+	var texts []string
+	for resp := range respCh {
+		texts = append(texts, resp.Text)
+	}
+
+	return texts
+}
+
+func TestCounter(t *testing.T) {
+	env := newTestEnv()
+
+	counter := func(op string) bot.Handler {
+		return newTestHandler(t, Counter, env, config.Settings{
+			"name": "deaths",
+			"op":   op,
+			"step": 2,
+		})
+	}
+
+	show, inc, set, reset := counter("show"), counter("inc"), counter("set"), counter("reset")
+
+	assertEq(t, []string{"deaths: 0"}, serve(show, "viewer", "!deaths", false))
+	assertEq(t, []string{"deaths: 2"}, serve(inc, "streamer", "!death", true))
+	assertEq(t, []string{"deaths: 4"}, serve(inc, "streamer", "!death", true))
+
+	// changes are not allowed to viewers
+	assertEq(t, []string(nil), serve(inc, "viewer", "!death", false))
+
+	// value is persisted in store of the channel
+	assertEq(t, int64(4), store.Counter(env.Store, "deaths"))
+	assertEq(t, []string{"deaths: 4"}, serve(show, "viewer", "!deaths", false))
+
+	assertEq(t, []string{"Error: value must be a number"}, serve(set, "streamer", "!setdeaths x", true))
+	assertEq(t, []string{"deaths: 10"}, serve(set, "streamer", "!setdeaths 10", true))
+	assertEq(t, int64(10), store.Counter(env.Store, "deaths"))
+
+	assertEq(t, []string{"deaths: 0"}, serve(reset, "streamer", "!resetdeaths", true))
+	assertEq(t, int64(0), store.Counter(env.Store, "deaths"))
+}
@@ -1,18 +1,19 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"log"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/disintegration/gift"
 	"github.com/ihrk/dots"
+	"github.com/ihrk/microbot/internal/backoff"
 	"github.com/ihrk/microbot/internal/bot"
 	"github.com/ihrk/microbot/internal/cache"
 	"github.com/ihrk/microbot/internal/config"
@@ -21,13 +22,19 @@ import (
 	"github.com/ihrk/microbot/internal/irc"
 )
 
-func Draw(_ config.Settings) bot.Handler {
+func Draw(cfg config.Settings, _ *bot.Env) (bot.Handler, error) {
+	// draw has no settings, but typos still have to be reported
+	err := cfg.Decode()
+	if err != nil {
+		return nil, err
+	}
+
 	ees := newExtensionEmoteStorage()
 
 	return bot.HandlerFunc(func(s *bot.Sender) {
-		emoteURL, ok := getTwitchEmoteURL(s.Msg.Tags)
+		emoteURL, ok := getTwitchEmoteURL(s.Msg)
 		if !ok {
-			emoteURL, ok = ees.getURL(s.Msg)
+			emoteURL, ok = ees.getURL(s.Context(), s.Msg)
 		}
 
 		if !ok {
@@ -44,7 +51,7 @@ func Draw(_ config.Settings) bot.Handler {
 			}
 		}()
 
-		resp, err := http.Get(emoteURL)
+		resp, err := backoff.Get(s.Context(), backoff.HTTP, emoteURL)
 		if err != nil {
 			log.Printf("get emote request error: %v\n", err)
 			return
@@ -89,27 +96,19 @@ func Draw(_ config.Settings) bot.Handler {
 		p = p.SubImage(image.Rect(0, 0, 30, 15))
 
 		s.Reply(p.String())
-	})
-}
-
-func getTwitchEmoteID(tags map[string]string) (string, bool) {
-	emotes := tags["emotes"]
-	end := strings.Index(emotes, ":")
-	if end == -1 {
-		return "", false
-	}
-	return emotes[:end], true
+	}), nil
 }
 
 const urlPattern = "https://static-cdn.jtvnw.net/emoticons/v2/%v/default/dark/3.0"
 
-func getTwitchEmoteURL(tags map[string]string) (string, bool) {
-	emoteID, ok := getTwitchEmoteID(tags)
-	if !ok {
+// getTwitchEmoteURL returns url of the first emote in message.
+func getTwitchEmoteURL(msg *irc.Msg) (string, bool) {
+	emotes := msg.Emotes()
+	if len(emotes) == 0 {
 		return "", false
 	}
 
-	return fmt.Sprintf(urlPattern, emoteID), true
+	return fmt.Sprintf(urlPattern, emotes[0].ID), true
 }
 
 type extensionEmoteStorage struct {
@@ -120,9 +119,9 @@ func newExtensionEmoteStorage() *extensionEmoteStorage {
 	return &extensionEmoteStorage{cache.New()}
 }
 
-func (s *extensionEmoteStorage) getURL(msg *irc.Msg) (string, bool) {
-	roomID, ok := msg.Tags["room-id"]
-	if !ok {
+func (s *extensionEmoteStorage) getURL(ctx context.Context, msg *irc.Msg) (string, bool) {
+	roomID := msg.RoomID()
+	if roomID == "" {
 		log.Printf("room id not found in message: %s\n", msg.Raw)
 		return "", false
 	}
@@ -131,7 +130,7 @@ func (s *extensionEmoteStorage) getURL(msg *irc.Msg) (string, bool) {
 
 	v, ok := s.c.Get(roomID)
 	if !ok {
-		emoteMap, ok = s.collectEmotes(roomID)
+		emoteMap, ok = s.collectEmotes(ctx, roomID)
 		if !ok {
 			return "", false
 		}
@@ -151,28 +150,28 @@ func (s *extensionEmoteStorage) getURL(msg *irc.Msg) (string, bool) {
 	return "", false
 }
 
-func (s *extensionEmoteStorage) collectEmotes(roomID string) (map[string]emote, bool) {
+func (s *extensionEmoteStorage) collectEmotes(ctx context.Context, roomID string) (map[string]emote, bool) {
 	log.Println("collecting extension emotes...")
 
-	bttvGlobalEmotes, err := bttv.GetGlobalEmotes()
+	bttvGlobalEmotes, err := bttv.GetGlobalEmotes(ctx)
 	if err != nil {
 		log.Printf("bttv global emotes request failed with error: %v", err)
 		return nil, false
 	}
 
-	ffzGlobalEmotes, err := ffz.GetGlobalEmotes()
+	ffzGlobalEmotes, err := ffz.GetGlobalEmotes(ctx)
 	if err != nil {
 		log.Printf("ffz global emotes request failed with error: %v", err)
 		return nil, false
 	}
 
-	bttvUserEmotes, err := bttv.GetUserEmotes(roomID)
+	bttvUserEmotes, err := bttv.GetUserEmotes(ctx, roomID)
 	if err != nil {
 		log.Printf("bttv user emotes request failed with error: %v", err)
 		return nil, false
 	}
 
-	ffzUserEmotes, err := ffz.GetUserEmotes(roomID)
+	ffzUserEmotes, err := ffz.GetUserEmotes(ctx, roomID)
 	if err != nil {
 		log.Printf("ffz user emotes request failed with error: %v", err)
 		return nil, false
@@ -1,7 +1,9 @@
 package actions
 
 import (
+	"context"
 	"log"
+	"sync"
 
 	"github.com/ihrk/microbot/internal/bot"
 	"github.com/ihrk/microbot/internal/config"
@@ -14,38 +16,49 @@ const (
 	queueFlex = "flex"
 )
 
-var queueTypes = []string{
-	queueSolo,
-	queueFlex,
-}
-
 var queueTypeMap = map[string]string{
 	queueSolo: "RANKED_SOLO_5x5",
 	queueFlex: "RANKED_FLEX_SR",
 }
 
-func Elo(cfg config.Settings) bot.Handler {
-	region := cfg.MustString("region")
-	summonerName := cfg.MustString("summonerName")
-	queueType := cfg.StringFromSetWithDefault("queueType", queueTypes, queueSolo)
+type eloSettings struct {
+	Region       string `config:"region,required"`
+	SummonerName string `config:"summonerName,required"`
+	QueueType    string `config:"queueType" default:"solo" oneof:"solo flex"`
+}
+
+func Elo(cfg config.Settings, env *bot.Env) (bot.Handler, error) {
+	var opts eloSettings
 
-	tp, ok := queueTypeMap[queueType]
-	if !ok {
-		log.Fatalf("unknown lol queue type: %s\n", queueType)
+	err := cfg.Decode(&opts)
+	if err != nil {
+		return nil, err
 	}
 
-	c, err := riot.NewClient(region, creds.RiotAPIKey())
+	key, err := env.Creds.Get(creds.KeyRiotAPIKey)
 	if err != nil {
-		log.Fatalf("riot client error: %v\n", err)
+		return nil, err
 	}
 
-	summoner, err := c.GetSummonerByName(summonerName)
+	tp := queueTypeMap[opts.QueueType]
+
+	c, err := riot.NewClient(opts.Region, key)
 	if err != nil {
-		log.Fatalf("riot client error: %v\n", err)
+		return nil, config.Errorf("settings.region", "%w", err)
 	}
 
+	// summoner is looked up on first use, so building
+	// of handlers does not wait for riot api
+	summoner := &summonerID{c: c, name: opts.SummonerName}
+
 	return bot.HandlerFunc(func(s *bot.Sender) {
-		entries, err := c.GetLeagueEntriesBySummoner(summoner.ID)
+		id, err := summoner.get(s.Context())
+		if err != nil {
+			log.Printf("riot api error: %v\n", err)
+			return
+		}
+
+		entries, err := c.GetLeagueEntriesBySummoner(s.Context(), id)
 		if err != nil {
 			log.Printf("riot api error: %v\n", err)
 			return
@@ -65,5 +78,32 @@ func Elo(cfg config.Settings) bot.Handler {
 		}
 
 		s.Reply(entry.String())
-	})
+	}), nil
+}
+
+// summonerID caches id of summoner after successful lookup.
+type summonerID struct {
+	c    *riot.Client
+	name string
+
+	m  sync.Mutex
+	id string
+}
+
+func (sid *summonerID) get(ctx context.Context) (string, error) {
+	sid.m.Lock()
+	defer sid.m.Unlock()
+
+	if sid.id != "" {
+		return sid.id, nil
+	}
+
+	summoner, err := sid.c.GetSummonerByName(ctx, sid.name)
+	if err != nil {
+		return "", err
+	}
+
+	sid.id = summoner.ID
+
+	return sid.id, nil
 }
@@ -3,12 +3,27 @@ package actions
 import (
 	"github.com/ihrk/microbot/internal/bot"
 	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/tmpl"
 )
 
-func Print(cfg config.Settings) bot.Handler {
-	text := cfg.MustString("text")
+type printSettings struct {
+	Text string `config:"text,required"`
+}
+
+func Print(cfg config.Settings, env *bot.Env) (bot.Handler, error) {
+	var opts printSettings
+
+	err := cfg.Decode(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := tmpl.Parse(opts.Text)
+	if err != nil {
+		return nil, config.Errorf("settings.text", "%w", err)
+	}
 
 	return bot.HandlerFunc(func(s *bot.Sender) {
-		s.Reply(text)
-	})
+		s.Reply(t.Execute(env.Data(s.Msg)))
+	}), nil
 }
@@ -0,0 +1,296 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ihrk/microbot/internal/bot"
+	"github.com/ihrk/microbot/internal/config"
+	"github.com/ihrk/microbot/internal/store"
+	"github.com/ihrk/microbot/internal/tmpl"
+)
+
+const (
+	quotePrefix  = "quote/"
+	quoteSeqKey  = "quotes/seq"
+	quoteGameKey = "quotes/game"
+
+	quoteCmdAdd    = "add"
+	quoteCmdDel    = "del"
+	quoteCmdSearch = "search"
+	quoteCmdGame   = "game"
+
+	quoteDateLayout = "2006-01-02"
+)
+
+type quotesSettings struct {
+	bot.Roles
+
+	Reply string `config:"reply" default:"#{id}: {quote} ({game}, {date})"`
+	Game  string `config:"game"`
+}
+
+type quote struct {
+	ID     int       `json:"id"`
+	Text   string    `json:"text"`
+	Author string    `json:"author"` // user who added quote
+	Date   time.Time `json:"date"`
+	Game   string    `json:"game"`
+}
+
+type quotes struct {
+	env   *bot.Env
+	s     store.Store
+	reply *tmpl.Template
+	allow bot.Roles
+	game  string
+
+	m   sync.Mutex
+	rnd *rand.Rand
+}
+
+// Quotes keeps quotes of the channel, subcommands are parsed from
+// message text: "!quote" replies random quote, "!quote 42" replies
+// quote by id, "!quote search word" replies random quote containing
+// word. Subcommands "add <text>", "del <id>" and "game <name>" are
+// allowed only for roles from settings.
+func Quotes(cfg config.Settings, env *bot.Env) (bot.Handler, error) {
+	var opts quotesSettings
+
+	err := cfg.Decode(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := tmpl.Parse(opts.Reply, "id", "quote", "author", "game", "date")
+	if err != nil {
+		return nil, config.Errorf("settings.reply", "%w", err)
+	}
+
+	q := &quotes{
+		env:   env,
+		s:     env.Store,
+		reply: reply,
+		allow: opts.Roles,
+		game:  opts.Game,
+		rnd:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	return bot.HandlerFunc(q.serve), nil
+}
+
+func (q *quotes) serve(s *bot.Sender) {
+	var args []string
+	if words := strings.Fields(s.Msg.Text); len(words) > 1 {
+		args = words[1:]
+	}
+
+	if len(args) == 0 {
+		q.replyRandom(s, "")
+		return
+	}
+
+	sub, rest := args[0], strings.Join(args[1:], " ")
+
+	switch sub {
+	case quoteCmdAdd, quoteCmdDel, quoteCmdGame:
+		if !q.allow.Allow(s.Msg) {
+			return
+		}
+	}
+
+	var err error
+
+	switch sub {
+	case quoteCmdAdd:
+		err = q.add(s, rest)
+	case quoteCmdDel:
+		err = q.del(s, rest)
+	case quoteCmdGame:
+		err = q.setGame(s, rest)
+	case quoteCmdSearch:
+		q.replyRandom(s, rest)
+	default:
+		q.replyByID(s, sub)
+	}
+
+	if err != nil {
+		log.Printf("quotes update error: %v\n", err)
+		s.Reply("Error: try again later")
+	}
+}
+
+func (q *quotes) add(s *bot.Sender, text string) error {
+	if text == "" {
+		s.Reply("Error: quote text is required")
+		return nil
+	}
+
+	game, ok := q.s.Get(quoteGameKey)
+	if !ok {
+		game = q.game
+	}
+
+	var id int
+
+	err := q.s.Update(quoteSeqKey, func(v string, _ bool) (string, error) {
+		id, _ = strconv.Atoi(v)
+		id++
+
+		return strconv.Itoa(id), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(quote{
+		ID:     id,
+		Text:   text,
+		Author: s.Msg.User,
+		Date:   time.Now(),
+		Game:   game,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = q.s.Set(quoteKey(id), string(data))
+	if err != nil {
+		return err
+	}
+
+	s.Reply(fmt.Sprintf("Quote #%d added", id))
+
+	return nil
+}
+
+func (q *quotes) del(s *bot.Sender, rawID string) error {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		s.Reply("Error: quote id must be a number")
+		return nil
+	}
+
+	if _, ok := q.s.Get(quoteKey(id)); !ok {
+		s.Reply("Quote not found")
+		return nil
+	}
+
+	err = q.s.Delete(quoteKey(id))
+	if err != nil {
+		return err
+	}
+
+	s.Reply(fmt.Sprintf("Quote #%d deleted", id))
+
+	return nil
+}
+
+func (q *quotes) setGame(s *bot.Sender, game string) error {
+	if game == "" {
+		err := q.s.Delete(quoteGameKey)
+		if err != nil {
+			return err
+		}
+
+		s.Reply("Game for new quotes is reset")
+
+		return nil
+	}
+
+	err := q.s.Set(quoteGameKey, game)
+	if err != nil {
+		return err
+	}
+
+	s.Reply(fmt.Sprintf("Game for new quotes: %s", game))
+
+	return nil
+}
+
+func (q *quotes) replyByID(s *bot.Sender, rawID string) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		s.Reply("Error: unknown subcommand")
+		return
+	}
+
+	qt, ok := q.get(quoteKey(id))
+	if !ok {
+		s.Reply("Quote not found")
+		return
+	}
+
+	q.replyQuote(s, qt)
+}
+
+// replyRandom replies random quote which text contains word,
+// empty word matches every quote.
+func (q *quotes) replyRandom(s *bot.Sender, word string) {
+	word = strings.ToLower(word)
+
+	var found []*quote
+
+	for _, key := range q.s.Keys(quotePrefix) {
+		qt, ok := q.get(key)
+		if ok && strings.Contains(strings.ToLower(qt.Text), word) {
+			found = append(found, qt)
+		}
+	}
+
+	if len(found) == 0 {
+		s.Reply("Quote not found")
+		return
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].ID < found[j].ID
+	})
+
+	q.m.Lock()
+	i := q.rnd.Intn(len(found))
+	q.m.Unlock()
+
+	q.replyQuote(s, found[i])
+}
+
+func (q *quotes) replyQuote(s *bot.Sender, qt *quote) {
+	data := q.env.Data(s.Msg)
+	data.Vars = map[string]string{
+		"id":     strconv.Itoa(qt.ID),
+		"quote":  qt.Text,
+		"author": qt.Author,
+		"game":   qt.Game,
+		"date":   qt.Date.Format(quoteDateLayout),
+	}
+
+	s.Reply(q.reply.Execute(data))
+}
+
+func (q *quotes) get(key string) (*quote, bool) {
+	v, ok := q.s.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	var qt quote
+
+	err := json.Unmarshal([]byte(v), &qt)
+	if err != nil {
+		log.Printf("quote decode error: %v\n", err)
+		return nil, false
+	}
+
+	return &qt, true
+}
+
+func quoteKey(id int) string {
+	return quotePrefix + strconv.Itoa(id)
+}
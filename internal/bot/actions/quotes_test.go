@@ -0,0 +1,50 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/ihrk/microbot/internal/config"
+)
+
+func TestQuotes(t *testing.T) {
+	env := newTestEnv()
+
+	h := newTestHandler(t, Quotes, env, config.Settings{
+		"reply": "#{id}: {quote}",
+	})
+
+	assertEq(t, []string{"Quote not found"}, serve(h, "viewer", "!quote", false))
+
+	assertEq(t, []string{"Quote #1 added"}, serve(h, "streamer", "!quote add first one", true))
+	assertEq(t, []string{"Quote #2 added"}, serve(h, "streamer", "!quote add second one", true))
+	assertEq(t, []string{"Quote #3 added"}, serve(h, "streamer", "!quote add third one", true))
+
+	// changes are not allowed to viewers
+	assertEq(t, []string(nil), serve(h, "viewer", "!quote add mine", false))
+	assertEq(t, []string(nil), serve(h, "viewer", "!quote del 1", false))
+
+	assertEq(t, []string{"#2: second one"}, serve(h, "viewer", "!quote 2", false))
+	assertEq(t, []string{"Quote not found"}, serve(h, "viewer", "!quote 42", false))
+	assertEq(t, []string{"Error: unknown subcommand"}, serve(h, "viewer", "!quote what", false))
+
+	assertEq(t, []string{"#3: third one"}, serve(h, "viewer", "!quote search THIRD", false))
+	assertEq(t, []string{"Quote not found"}, serve(h, "viewer", "!quote search fourth", false))
+
+	assertEq(t, []string{"Quote #3 deleted"}, serve(h, "streamer", "!quote del 3", true))
+	assertEq(t, []string{"Quote not found"}, serve(h, "streamer", "!quote del 3", true))
+	assertEq(t, []string{"Quote not found"}, serve(h, "viewer", "!quote 3", false))
+
+	// ids of deleted quotes are not reused
+	assertEq(t, []string{"Quote #4 added"}, serve(h, "streamer", "!quote add fourth one", true))
+
+	seq, _ := env.Store.Get(quoteSeqKey)
+	assertEq(t, "4", seq)
+
+	assertEq(t, []string{"Quote #1 deleted"}, serve(h, "streamer", "!quote del 1", true))
+	assertEq(t, []string{"Quote #2 deleted"}, serve(h, "streamer", "!quote del 2", true))
+	assertEq(t, []string{"Quote #4 deleted"}, serve(h, "streamer", "!quote del 4", true))
+
+	// sequence is kept when every quote is deleted
+	assertEq(t, []string{"Quote #5 added"}, serve(h, "streamer", "!quote add fifth one", true))
+	assertEq(t, []string{"#5: fifth one"}, serve(h, "viewer", "!quote", false))
+}
@@ -13,8 +13,17 @@ import (
 
 var urlExpr = regexp.MustCompile(`(http(s)?:\/\/.)?(www\.)?[-a-zA-Z0-9@:%._+~#=]{2,256}\.[a-z]{2,6}\b([-a-zA-Z0-9@:%_+.~#?&/=]*)`)
 
-func SongRequest(cfg config.Settings) bot.Handler {
-	requestCmd := cfg.MustString("requestCmd")
+type songRequestSettings struct {
+	RequestCmd string `config:"requestCmd,required"`
+}
+
+func SongRequest(cfg config.Settings, _ *bot.Env) (bot.Handler, error) {
+	var opts songRequestSettings
+
+	err := cfg.Decode(&opts)
+	if err != nil {
+		return nil, err
+	}
 
 	return bot.HandlerFunc(func(s *bot.Sender) {
 		rawURL := urlExpr.FindString(s.Msg.Text)
@@ -24,9 +33,9 @@ func SongRequest(cfg config.Settings) bot.Handler {
 			return
 		}
 
-		text := fmt.Sprintf("%s %s", requestCmd, videoID)
+		text := fmt.Sprintf("%s %s", opts.RequestCmd, videoID)
 		s.Send(text)
-	})
+	}), nil
 }
 
 func getYoutubeVideoID(rawURL string) (string, error) {
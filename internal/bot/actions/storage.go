@@ -1,26 +1,26 @@
 package actions
 
 import (
-	"log"
-
 	"github.com/ihrk/microbot/internal/bot"
 	"github.com/ihrk/microbot/internal/config"
 )
 
-type Storage map[string]func(cfg config.Settings) bot.Handler
+type Storage map[string]func(cfg config.Settings, env *bot.Env) (bot.Handler, error)
 
 var defaultStorage = Storage{
 	"print":       Print,
 	"elo":         Elo,
 	"songRequest": SongRequest,
 	"draw":        Draw,
+	"counter":     Counter,
+	"quotes":      Quotes,
 }
 
-func New(cfg *config.Feature) bot.Handler {
+func New(cfg *config.Feature, env *bot.Env) (bot.Handler, error) {
 	b, ok := defaultStorage[cfg.Type]
 	if !ok {
-		log.Fatalf("unknown action: %v\n", cfg.Type)
+		return nil, config.Errorf("type", "unknown action: %v", cfg.Type)
 	}
 
-	return b(cfg.Settings)
+	return b(cfg.Settings, env)
 }
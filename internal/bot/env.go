@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"log"
+
+	"github.com/ihrk/microbot/internal/creds"
+	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/store"
+	"github.com/ihrk/microbot/internal/tmpl"
+)
+
+// Env carries channel scoped dependencies of actions and middlewares.
+type Env struct {
+	Channel string
+	Store   store.Store
+	Creds   *creds.Creds
+
+	// ReadOnly is set if messages must not be sent to the channel.
+	ReadOnly bool
+}
+
+func NewEnv(channel string, st store.Store, cr *creds.Creds) *Env {
+	return &Env{
+		Channel: channel,
+		Store:   st,
+		Creds:   cr,
+	}
+}
+
+// Data returns template data for msg, template counters are kept in store.
+func (env *Env) Data(msg *irc.Msg) *tmpl.Data {
+	return &tmpl.Data{
+		Msg:      msg,
+		Counters: storeCounters{env.Store},
+	}
+}
+
+type storeCounters struct {
+	s store.Store
+}
+
+func (c storeCounters) Add(name string, delta int64) int64 {
+	n, err := store.AddCounter(c.s, name, delta)
+	if err != nil {
+		log.Printf("counter update error: %v\n", err)
+	}
+
+	return n
+}
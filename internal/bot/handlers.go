@@ -95,8 +95,20 @@ func MatchCmd(msg *irc.Msg) (string, bool) {
 }
 
 func MatchReward(msg *irc.Msg) (string, bool) {
-	rewardID, ok := msg.Tags["custom-reward-id"]
-	return rewardID, ok
+	rewardID := msg.RewardID()
+	return rewardID, rewardID != ""
+}
+
+// MatchEvent matches USERNOTICE messages by event type,
+// see irc.Event* constants.
+func MatchEvent(msg *irc.Msg) (string, bool) {
+	if msg.Type != irc.MsgTypeUserNotice {
+		return "", false
+	}
+
+	eventType := msg.Tags["msg-id"]
+
+	return eventType, eventType != ""
 }
 
 type SingleRouter struct {
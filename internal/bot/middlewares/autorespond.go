@@ -1,25 +1,41 @@
 package middlewares
 
 import (
+	"time"
+
 	"github.com/ihrk/microbot/internal/bot"
 	"github.com/ihrk/microbot/internal/config"
 	"github.com/ihrk/microbot/internal/cooldown"
+	"github.com/ihrk/microbot/internal/tmpl"
 )
 
-func Autorespond(cfg config.Settings) bot.Middleware {
-	text := cfg.MustString("text")
+type autorespondSettings struct {
+	Text   string        `config:"text,required"`
+	Period time.Duration `config:"period,required" min:"1s"`
+	Gap    int           `config:"gap" min:"0"`
+}
 
-	per := cfg.MustDuration("period")
-	gap, _ := cfg.Int("gap")
+func Autorespond(cfg config.Settings, env *bot.Env) (bot.Middleware, error) {
+	var opts autorespondSettings
 
-	cd := cooldown.New(per, gap)
+	err := cfg.Decode(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := tmpl.Parse(opts.Text)
+	if err != nil {
+		return nil, config.Errorf("settings.text", "%w", err)
+	}
+
+	cd := cooldown.New(opts.Period, opts.Gap)
 
 	return func(next bot.Handler) bot.Handler {
 		return bot.HandlerFunc(func(s *bot.Sender) {
 			if cd.Check() {
-				s.Send(text)
+				s.Send(t.Execute(env.Data(s.Msg)))
 			}
 			next.Serve(s)
 		})
-	}
+	}, nil
 }
@@ -1,9 +1,7 @@
 package middlewares
 
 import (
-	"log"
 	"regexp"
-	"strings"
 	"time"
 	"unicode"
 
@@ -11,6 +9,7 @@ import (
 	"github.com/ihrk/microbot/internal/config"
 	"github.com/ihrk/microbot/internal/irc"
 	"github.com/ihrk/microbot/internal/limit"
+	"github.com/ihrk/microbot/internal/tmpl"
 )
 
 type filter struct {
@@ -19,40 +18,95 @@ type filter struct {
 
 	passThrough bool
 
-	allowMod     bool
-	allowVIP     bool
-	allowSub     bool
+	allow        bot.Roles
 	allowRewards []string
 }
 
 type filterFunc func(*irc.Msg) bool
 
-func Filter(cfg config.Settings) bot.Middleware {
-	var f filter
+const (
+	penaltyDeleteMsg = "deleteMsg"
+	penaltyTimeout   = "timeout"
+	penaltyBan       = "ban"
+)
+
+// filterSettings are common for all filter types, settings
+// of particular type are decoded into its filterSpec.
+type filterSettings struct {
+	bot.Roles
 
-	f.h = newFilterHandler(cfg)
-	f.ff = newFilterFunc(cfg)
+	Type         string        `config:"type,required"`
+	PassThrough  bool          `config:"passThrough"`
+	AllowRewards []string      `config:"allowRewards"`
+	Penalty      string        `config:"penalty" oneof:"deleteMsg timeout ban"`
+	Duration     time.Duration `config:"duration" min:"1s"`
+	Reply        string        `config:"reply"`
+	Reason       string        `config:"reason"`
+}
 
-	f.passThrough = cfg.Bool("passThrough")
+func (opts *filterSettings) Validate() error {
+	if opts.Penalty == penaltyTimeout && opts.Duration == 0 {
+		return config.Errorf("duration", "duration is required for timeout")
+	}
 
-	f.allowMod = cfg.Bool("allowMod")
-	f.allowVIP = cfg.Bool("allowVIP")
-	f.allowSub = cfg.Bool("allowSub")
+	return nil
+}
 
-	f.allowRewards = cfg.Strings("allowRewards")
+// filterSpec is settings of filter type which builds filterFunc.
+type filterSpec interface {
+	filterFunc() filterFunc
+}
 
-	return f.mw
+var filterSpecStorage = map[string]func() filterSpec{
+	"byUsername": func() filterSpec { return new(byUsername) },
+	"countLimit": func() filterSpec { return new(countLimit) },
+	"limitChars": func() filterSpec { return new(limitChars) },
+	"blockLinks": pureFunc(blockLinks),
+	"blockAll":   pureFunc(blockAll),
+}
+
+func Filter(cfg config.Settings, env *bot.Env) (bot.Middleware, error) {
+	filterType, err := cfg.RequiredString("type")
+	if err != nil {
+		return nil, err
+	}
+
+	newSpec, found := filterSpecStorage[filterType]
+	if !found {
+		return nil, config.Errorf("settings.type", "filter type not found: %s", filterType)
+	}
+
+	var opts filterSettings
+
+	spec := newSpec()
+
+	err = cfg.Decode(&opts, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := newFilterHandler(&opts, env)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &filter{
+		ff:           spec.filterFunc(),
+		h:            h,
+		passThrough:  opts.PassThrough,
+		allow:        opts.Roles,
+		allowRewards: opts.AllowRewards,
+	}
+
+	return f.mw, nil
 }
 
 func (f *filter) mw(next bot.Handler) bot.Handler {
 	return bot.HandlerFunc(func(s *bot.Sender) {
-		rewardUUID, isReward := getReward(s.Msg)
+		rewardID := s.Msg.RewardID()
 
-		ok := isBroadcaster(s.Msg) ||
-			f.allowMod && isMod(s.Msg) ||
-			f.allowVIP && isVIP(s.Msg) ||
-			f.allowSub && isSub(s.Msg) ||
-			isReward && elem(rewardUUID, f.allowRewards) ||
+		ok := f.allow.Allow(s.Msg) ||
+			rewardID != "" && elem(rewardID, f.allowRewards) ||
 			f.ff(s.Msg)
 
 		if !ok {
@@ -65,102 +119,61 @@ func (f *filter) mw(next bot.Handler) bot.Handler {
 	})
 }
 
-func pureFunc(ff filterFunc) func(config.Settings) filterFunc {
-	return func(_ config.Settings) filterFunc {
-		return ff
-	}
+// pureSpec is spec of filter without settings.
+type pureSpec struct {
+	ff filterFunc
 }
 
-var filterFuncStorage = map[string]func(config.Settings) filterFunc{
-	"byUsername": byUsername,
-	"countLimit": countLimit,
-	"limitChars": limitChars,
-	"blockLinks": pureFunc(blockLinks),
-	"blockAll":   pureFunc(blockAll),
+func (p *pureSpec) filterFunc() filterFunc {
+	return p.ff
 }
 
-func newFilterFunc(cfg config.Settings) filterFunc {
-	filterType := cfg.MustString("type")
-
-	bf, found := filterFuncStorage[filterType]
-	if !found {
-		log.Fatalf("filter type not found: %s\n", filterType)
+func pureFunc(ff filterFunc) func() filterSpec {
+	return func() filterSpec {
+		return &pureSpec{ff}
 	}
-
-	return bf(cfg)
 }
 
-const (
-	penaltyDeleteMsg = "deleteMsg"
-	penaltyTimeout   = "timeout"
-	penaltyBan       = "ban"
-)
-
-var penaltyTypes = []string{
-	penaltyDeleteMsg,
-	penaltyTimeout,
-	penaltyBan,
-}
+func newFilterHandler(opts *filterSettings, env *bot.Env) (bot.Handler, error) {
+	var reply *tmpl.Template
 
-func newFilterHandler(cfg config.Settings) bot.Handler {
-	penalty, _ := cfg.StringFromSet("penalty", penaltyTypes)
+	if opts.Reply != "" {
+		var err error
 
-	var duration time.Duration
-	if penalty == penaltyTimeout {
-		duration = cfg.MustDuration("duration")
+		reply, err = tmpl.Parse(opts.Reply)
+		if err != nil {
+			return nil, config.Errorf("settings.reply", "%w", err)
+		}
 	}
 
-	replyText, hasReply := cfg.String("reply")
-
-	reason, _ := cfg.String("reason")
-
 	return bot.HandlerFunc(func(s *bot.Sender) {
-		switch penalty {
+		switch opts.Penalty {
 		case penaltyDeleteMsg:
 			s.Delete()
 		case penaltyTimeout:
-			s.Timeout(duration, reason)
+			s.Timeout(opts.Duration, opts.Reason)
 		case penaltyBan:
-			s.Ban(reason)
+			s.Ban(opts.Reason)
 		}
 
-		if hasReply {
-			s.Reply(replyText)
+		if reply != nil {
+			s.Reply(reply.Execute(env.Data(s.Msg)))
 		}
-	})
+	}), nil
 }
 
-func countLimit(cfg config.Settings) filterFunc {
-	lim := cfg.MustInt("limitAmount")
-	per := cfg.MustDuration("limitPeriod")
+type countLimit struct {
+	LimitAmount int           `config:"limitAmount,required" min:"1"`
+	LimitPeriod time.Duration `config:"limitPeriod,required" min:"1s"`
+}
 
-	l := limit.New(lim, per)
+func (cl *countLimit) filterFunc() filterFunc {
+	l := limit.New(cl.LimitAmount, cl.LimitPeriod)
 	return func(msg *irc.Msg) bool {
 		return l.Add(1)
 	}
 }
 
-func isBroadcaster(msg *irc.Msg) bool {
-	return strings.Contains(msg.Tags["badges"], "broadcaster")
-}
-
-func isMod(msg *irc.Msg) bool {
-	return strings.Contains(msg.Tags["badges"], "moderator")
-}
-
-func isVIP(msg *irc.Msg) bool {
-	return strings.Contains(msg.Tags["badges"], "vip")
-}
-
-func isSub(msg *irc.Msg) bool {
-	return strings.Contains(msg.Tags["badges"], "subscriber")
-}
-
-func getReward(msg *irc.Msg) (string, bool) {
-	s, ok := msg.Tags["custom-reward-id"]
-	return s, ok
-}
-
 func elem(s string, a []string) bool {
 	for i := range a {
 		if s == a[i] {
@@ -171,8 +184,12 @@ func elem(s string, a []string) bool {
 	return false
 }
 
-func byUsername(cfg config.Settings) filterFunc {
-	name := cfg.MustString("username")
+type byUsername struct {
+	Username string `config:"username,required"`
+}
+
+func (bu *byUsername) filterFunc() filterFunc {
+	name := bu.Username
 
 	return func(msg *irc.Msg) bool {
 		return msg.User == name
@@ -189,21 +206,19 @@ func countFunc(s string, f func(rune) bool) int {
 	return n
 }
 
-func limitChars(cfg config.Settings) filterFunc {
-	tp := cfg.MustString("charType")
-	var charFunc func(rune) bool
-	switch tp {
-	case "symbol":
-		charFunc = unicode.IsSymbol
-	case "upper":
-		charFunc = unicode.IsUpper
-	case "mark":
-		charFunc = unicode.IsMark
-	default:
-		log.Fatalf("unknown character type: %s\n", tp)
-	}
+var charFuncs = map[string]func(rune) bool{
+	"symbol": unicode.IsSymbol,
+	"upper":  unicode.IsUpper,
+	"mark":   unicode.IsMark,
+}
+
+type limitChars struct {
+	CharType  string `config:"charType,required" oneof:"symbol upper mark"`
+	CharLimit int    `config:"charLimit,required" min:"0"`
+}
 
-	limit := cfg.MustInt("charLimit")
+func (lc *limitChars) filterFunc() filterFunc {
+	charFunc, limit := charFuncs[lc.CharType], lc.CharLimit
 
 	return func(msg *irc.Msg) bool {
 		return countFunc(msg.Text, charFunc) <= limit
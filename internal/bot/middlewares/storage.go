@@ -1,29 +1,22 @@
 package middlewares
 
 import (
-	"log"
-
 	"github.com/ihrk/microbot/internal/bot"
 	"github.com/ihrk/microbot/internal/config"
 )
 
-type Storage map[string]func(cfg config.Settings) bot.Middleware
+type Storage map[string]func(cfg config.Settings, env *bot.Env) (bot.Middleware, error)
 
 var defaultStorage = Storage{
 	"filter":      Filter,
 	"autorespond": Autorespond,
 }
 
-func New(cfgs []*config.Feature) bot.Middleware {
-	mws := make([]bot.Middleware, len(cfgs))
-
-	for i, cfg := range cfgs {
-		b, ok := defaultStorage[cfg.Type]
-		if !ok {
-			log.Fatalf("unknown middleware: %v\n", cfg.Type)
-		}
-		mws[i] = b(cfg.Settings)
+func New(cfg *config.Feature, env *bot.Env) (bot.Middleware, error) {
+	b, ok := defaultStorage[cfg.Type]
+	if !ok {
+		return nil, config.Errorf("type", "unknown middleware: %v", cfg.Type)
 	}
 
-	return bot.Concat(mws...)
+	return b(cfg.Settings, env)
 }
@@ -0,0 +1,206 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/ihrk/microbot/internal/irc"
+)
+
+const (
+	defaultWorkers = 8
+	defaultQueue   = 256
+	defaultTimeout = 30 * time.Second
+)
+
+// Options of message handling. Messages with the same lane key are
+// handled one by one in order of arrival, other messages are handled
+// concurrently by Workers goroutines.
+type Options struct {
+	Workers int
+	// Queue limits number of messages waiting for a worker,
+	// reading of connection is paused when queue is full.
+	Queue int
+	// Timeout of a single handler, context of the handler is done
+	// when it expires. Lane moves to the next message and worker is
+	// released only when the handler returns.
+	Timeout time.Duration
+	// Lane returns lane key of message, LaneByChannel by default.
+	Lane func(*irc.Msg) string
+	// ReadOnly server logs every response instead of sending it,
+	// e.g. when chat is read anonymously.
+	ReadOnly bool
+}
+
+func LaneByChannel(msg *irc.Msg) string {
+	return msg.Channel
+}
+
+func LaneByUser(msg *irc.Msg) string {
+	return msg.Channel + " " + msg.User
+}
+
+func (opts *Options) setDefaults() {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+
+	if opts.Queue <= 0 {
+		opts.Queue = defaultQueue
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	if opts.Lane == nil {
+		opts.Lane = LaneByChannel
+	}
+}
+
+type job func(ctx context.Context)
+
+type lane struct {
+	key  string
+	jobs []job
+}
+
+// pool runs jobs by fixed number of workers keeping order
+// of jobs within a lane.
+type pool struct {
+	ctx     context.Context // parent of job contexts, done by stop
+	cancel  context.CancelFunc
+	timeout time.Duration
+	slots   chan struct{} // taken by queued and running jobs
+	wg      sync.WaitGroup
+
+	m     sync.Mutex
+	c     *sync.Cond
+	lanes map[string]*lane // lanes with queued or running jobs
+	ready []*lane          // lanes which head job can be started
+}
+
+// newPool starts workers. Job contexts are not derived from context
+// of ListenAndServe on purpose: pool outlives connections, and
+// handlers running on shutdown are given time to finish by Drain,
+// which calls stop when its own context is done.
+func newPool(workers, queue int, timeout time.Duration) *pool {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p := &pool{
+		ctx:     ctx,
+		cancel:  cancel,
+		timeout: timeout,
+		slots:   make(chan struct{}, queue+workers),
+		lanes:   make(map[string]*lane),
+	}
+
+	p.c = sync.NewCond(&p.m)
+
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+// submit queues j to lane key, it blocks while pool is saturated.
+func (p *pool) submit(ctx context.Context, key string, j job) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	p.wg.Add(1)
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	l, ok := p.lanes[key]
+	if !ok {
+		l = &lane{key: key}
+		p.lanes[key] = l
+		p.ready = append(p.ready, l)
+		p.c.Signal()
+	}
+
+	l.jobs = append(l.jobs, j)
+
+	return nil
+}
+
+func (p *pool) work() {
+	for {
+		p.m.Lock()
+
+		for len(p.ready) == 0 {
+			p.c.Wait()
+		}
+
+		l := p.ready[0]
+		p.ready = p.ready[1:]
+		j := l.jobs[0]
+
+		p.m.Unlock()
+
+		p.run(l.key, j)
+
+		p.m.Lock()
+
+		l.jobs = l.jobs[1:]
+		if len(l.jobs) == 0 {
+			delete(p.lanes, l.key)
+		} else {
+			// lane goes to the end, so busy lanes do not starve others
+			p.ready = append(p.ready, l)
+			p.c.Signal()
+		}
+
+		p.m.Unlock()
+
+		<-p.slots
+		p.wg.Done()
+	}
+}
+
+// wait returns channel closed when all submitted jobs are finished.
+func (p *pool) wait() <-chan struct{} {
+	finished := make(chan struct{})
+
+	go func() {
+		p.wg.Wait()
+		close(finished)
+	}()
+
+	return finished
+}
+
+// stop cancels contexts of running and queued jobs.
+func (p *pool) stop() {
+	p.cancel()
+}
+
+// run runs j with context which is done after timeout, panic of j
+// is recovered. Worker is not released until j returns, even after
+// timeout, so handlers which ignore context can not pile up.
+func (p *pool) run(key string, j job) {
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
+	defer cancel()
+
+	expired := time.AfterFunc(p.timeout, func() {
+		log.Printf("handler in lane %q timed out after %v\n", key, p.timeout)
+	})
+	defer expired.Stop()
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("handler panic in lane %q: %v\n%s", key, r, debug.Stack())
+		}
+	}()
+
+	j(ctx)
+}
@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func assertEq(t *testing.T, expected, actual interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nexpected: '%v',\nactual: '%v'", expected, actual)
+	}
+}
+
+func TestPoolLaneOrder(t *testing.T) {
+	p := newPool(4, 16, time.Second)
+
+	var (
+		m   sync.Mutex
+		got = make(map[string][]int)
+	)
+
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		for _, key := range []string{"a", "b"} {
+			i, key := i, key
+
+			err := p.submit(ctx, key, func(context.Context) {
+				time.Sleep(time.Millisecond)
+
+				m.Lock()
+				got[key] = append(got[key], i)
+				m.Unlock()
+			})
+			assertEq(t, nil, err)
+		}
+	}
+
+	<-p.wait()
+
+	expected := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	assertEq(t, expected, got["a"])
+	assertEq(t, expected, got["b"])
+}
+
+func TestPoolRecoversAndTimesOut(t *testing.T) {
+	p := newPool(1, 4, 10*time.Millisecond)
+
+	ctx := context.Background()
+
+	var done bool
+
+	assertEq(t, nil, p.submit(ctx, "a", func(context.Context) { panic("handler failed") }))
+	assertEq(t, nil, p.submit(ctx, "a", func(ctx context.Context) { <-ctx.Done() }))
+	assertEq(t, nil, p.submit(ctx, "a", func(context.Context) { done = true }))
+
+	select {
+	case <-p.wait():
+	case <-time.After(time.Second):
+		t.Fatal("lane is stuck")
+	}
+
+	assertEq(t, true, done)
+}
+
+func TestPoolBackpressure(t *testing.T) {
+	p := newPool(1, 1, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assertEq(t, nil, p.submit(ctx, "a", func(ctx context.Context) { <-ctx.Done() }))
+	assertEq(t, nil, p.submit(ctx, "a", func(context.Context) {}))
+	assertEq(t, context.DeadlineExceeded, p.submit(ctx, "a", func(context.Context) {}))
+}
+
+func TestPoolKeepsWorkerAfterTimeout(t *testing.T) {
+	p := newPool(1, 4, 10*time.Millisecond)
+
+	ctx := context.Background()
+
+	var (
+		m                    sync.Mutex
+		returned, overlapped bool
+	)
+
+	// job ignores its context and returns long after timeout
+	assertEq(t, nil, p.submit(ctx, "a", func(context.Context) {
+		time.Sleep(50 * time.Millisecond)
+
+		m.Lock()
+		returned = true
+		m.Unlock()
+	}))
+	assertEq(t, nil, p.submit(ctx, "b", func(context.Context) {
+		m.Lock()
+		overlapped = !returned
+		m.Unlock()
+	}))
+
+	<-p.wait()
+
+	assertEq(t, false, overlapped)
+}
@@ -0,0 +1,21 @@
+package bot
+
+import "github.com/ihrk/microbot/internal/irc"
+
+// Roles lists chat roles that are allowed to do something,
+// broadcaster is always allowed. Tags allow to embed Roles
+// into settings structs of features.
+type Roles struct {
+	All bool `config:"allowAll"`
+	Mod bool `config:"allowMod"`
+	VIP bool `config:"allowVIP"`
+	Sub bool `config:"allowSub"`
+}
+
+func (r Roles) Allow(msg *irc.Msg) bool {
+	return r.All ||
+		msg.IsBroadcaster() ||
+		r.Mod && msg.IsMod() ||
+		r.VIP && msg.IsVIP() ||
+		r.Sub && msg.IsSub()
+}
@@ -3,15 +3,21 @@ package bot
 import (
 	"context"
 	"fmt"
+	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/ihrk/microbot/internal/irc"
 )
 
-type response struct {
-	channel     string
-	text        string
-	parentMsgID string
+// Response is message sent by handler, it is a reply
+// if ParentMsgID is set. ReadOnly response is logged by
+// server instead of being sent.
+type Response struct {
+	Channel     string
+	Text        string
+	ParentMsgID string
+	ReadOnly    bool
 }
 
 type Handler interface {
@@ -21,79 +27,245 @@ type Handler interface {
 type Middleware func(Handler) Handler
 
 type Server struct {
-	c *irc.Client
+	h        atomic.Value // handlerBox
+	respCh   chan Response
+	pool     *pool
+	lane     func(*irc.Msg) string
+	readOnly bool
+}
+
+// handlerBox keeps concrete type of atomic.Value the same
+// for every handler.
+type handlerBox struct {
 	h Handler
 }
 
-func NewServer(c *irc.Client, h Handler) *Server {
-	return &Server{
-		c: c,
-		h: h,
+const msgBuf = 10
+
+// NewServer creates server which can be used for several connections,
+// responses that are not sent before connection is lost are kept
+// for the next one. Zero fields of opts are set to defaults.
+func NewServer(h Handler, opts Options) *Server {
+	opts.setDefaults()
+
+	srv := &Server{
+		respCh:   make(chan Response, msgBuf),
+		pool:     newPool(opts.Workers, opts.Queue, opts.Timeout),
+		lane:     opts.Lane,
+		readOnly: opts.ReadOnly,
 	}
+
+	srv.SetHandler(h)
+
+	return srv
+}
+
+// SetHandler replaces handler of the server, messages that are
+// already being handled are finished by the old one.
+func (srv *Server) SetHandler(h Handler) {
+	srv.h.Store(handlerBox{h})
+}
+
+func (srv *Server) handler() Handler {
+	return srv.h.Load().(handlerBox).h
 }
 
-func (srv *Server) serve(respCh <-chan response) {
-	for resp := range respCh {
-		if resp.parentMsgID == "" {
-			srv.c.PrivMsg(resp.channel, resp.text)
-		} else {
-			srv.c.PrivMsgReply(resp.channel, resp.text, resp.parentMsgID)
+// Send puts text to the response queue of the server,
+// it is used to post messages not triggered by chat. Queue
+// is not read while disconnected, error of ctx is returned
+// if it is done before text is queued.
+func (srv *Server) Send(ctx context.Context, channel, text string) error {
+	select {
+	case srv.respCh <- Response{Channel: channel, Text: text}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (srv *Server) send(c *irc.Client, resp Response) {
+	if resp.ReadOnly || srv.readOnly {
+		log.Printf("read-only #%s, not sent: %s\n", resp.Channel, resp.Text)
+		return
+	}
+
+	if resp.ParentMsgID == "" {
+		c.PrivMsg(resp.Channel, resp.Text)
+	} else {
+		c.PrivMsgReply(resp.Channel, resp.Text, resp.ParentMsgID)
+	}
+}
+
+func (srv *Server) serve(c *irc.Client, done <-chan struct{}) {
+	for {
+		select {
+		case resp := <-srv.respCh:
+			srv.send(c, resp)
+		case <-done:
+			return
 		}
 	}
 }
 
-const msgBuf = 10
+// ListenAndServe handles messages of c until connection fails or ctx
+// is done. Connection is left open in the latter case, so Drain
+// can be called to finish handling.
+func (srv *Server) ListenAndServe(ctx context.Context, c *irc.Client) error {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
 
-func (srv *Server) ListenAndServe(ctx context.Context) error {
-	respCh := make(chan response, msgBuf)
+	go func() {
+		srv.serve(c, done)
+		close(stopped)
+	}()
 
-	go srv.serve(respCh)
+	defer func() {
+		close(done)
+		<-stopped
+	}()
 
-	defer close(respCh)
+	msgs := make(chan *irc.Msg)
+	errc := make(chan error, 1)
+
+	// reading is not bound to ctx, because cancelling
+	// read closes connection
+	go func() {
+		for {
+			msg, err := c.ReadMsg(context.Background())
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			select {
+			case msgs <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
 
 	for {
-		msg, err := srv.c.ReadMsg(ctx)
-		if err != nil {
+		select {
+		case msg := <-msgs:
+			h := srv.handler()
+
+			err := srv.pool.submit(ctx, srv.lane(msg), func(ctx context.Context) {
+				h.Serve(NewSender(ctx, msg, srv.respCh))
+			})
+			if err != nil {
+				return err
+			}
+		case err := <-errc:
 			return err
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+	}
+}
 
-		go srv.h.Serve(NewSender(msg, respCh))
+// Drain waits for running handlers and sends their responses to c.
+// Error of ctx is returned if handlers do not finish in time,
+// contexts of unfinished handlers are cancelled then.
+func (srv *Server) Drain(ctx context.Context, c *irc.Client) error {
+	finished := srv.pool.wait()
+
+	for {
+		select {
+		case resp := <-srv.respCh:
+			srv.send(c, resp)
+		case <-finished:
+			for {
+				select {
+				case resp := <-srv.respCh:
+					srv.send(c, resp)
+				default:
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			srv.pool.stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Wait waits until every handler returns, including ones cancelled
+// by Drain, error of ctx is returned if they do not return in time.
+func (srv *Server) Wait(ctx context.Context) error {
+	select {
+	case <-srv.pool.wait():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
 type Sender struct {
-	Msg    *irc.Msg
-	respCh chan<- response
+	Msg      *irc.Msg
+	ctx      context.Context
+	respCh   chan<- Response
+	readOnly bool
 }
 
-func NewSender(msg *irc.Msg, respCh chan<- response) *Sender {
+func NewSender(ctx context.Context, msg *irc.Msg, respCh chan<- Response) *Sender {
 	return &Sender{
 		Msg:    msg,
+		ctx:    ctx,
 		respCh: respCh,
 	}
 }
 
+// Context is done when handler times out or server shuts down,
+// it has to be passed to outbound requests of the handler.
+func (s *Sender) Context() context.Context {
+	return s.ctx
+}
+
 func (s *Sender) RewardID() string {
-	return s.Msg.Tags["custom-reward-id"]
+	return s.Msg.RewardID()
+}
+
+// ReadOnly marks responses of senders passed to next,
+// so they are logged instead of being sent.
+func ReadOnly(next Handler) Handler {
+	return HandlerFunc(func(s *Sender) {
+		ro := *s
+		ro.readOnly = true
+
+		next.Serve(&ro)
+	})
+}
+
+func (s *Sender) send(resp Response) {
+	resp.ReadOnly = s.readOnly
+	s.respCh <- resp
 }
 
 func (s *Sender) Send(text string) {
-	s.respCh <- response{
-		channel: s.Msg.Channel,
-		text:    text,
-	}
+	s.send(Response{
+		Channel: s.Msg.Channel,
+		Text:    text,
+	})
 }
 
+// Reply sends text as a reply to the message, messages other than
+// PRIVMSG can not be replied to, so text is just sent to the channel.
 func (s *Sender) Reply(text string) {
-	s.respCh <- response{
-		channel:     s.Msg.Channel,
-		text:        text,
-		parentMsgID: s.Msg.Tags["id"],
+	if s.Msg.Type != irc.MsgTypePrivMsg {
+		s.Send(text)
+		return
 	}
+
+	s.send(Response{
+		Channel:     s.Msg.Channel,
+		Text:        text,
+		ParentMsgID: s.Msg.ID(),
+	})
 }
 
 func (s *Sender) Delete() {
-	text := fmt.Sprintf("/delete %s", s.Msg.Tags["id"])
+	text := fmt.Sprintf("/delete %s", s.Msg.ID())
 	s.Send(text)
 }
 
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/irc/irctest"
+	"github.com/ihrk/microbot/internal/store"
+)
+
+func TestDrainTimeout(t *testing.T) {
+	fake := irctest.NewServer()
+	defer fake.Close()
+
+	c, err := irc.DialURL(context.Background(), fake.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	db := store.NewMemory()
+	st := db.Namespace("first")
+
+	started := make(chan struct{})
+
+	// handler writes to store only after it is cancelled
+	h := HandlerFunc(func(s *Sender) {
+		close(started)
+		<-s.Context().Done()
+
+		time.Sleep(20 * time.Millisecond)
+		assertEq(t, nil, st.Set("late", "write"))
+	})
+
+	srv := NewServer(h, Options{Timeout: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- srv.ListenAndServe(ctx, c)
+	}()
+
+	if err = fake.PrivMsg("first", "viewer", "hi", map[string]string{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("handler is not started")
+	}
+
+	cancel()
+	<-stopped
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer drainCancel()
+
+	assertEq(t, context.DeadlineExceeded, srv.Drain(drainCtx, c))
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+
+	// store can be closed after handlers cancelled by Drain return
+	assertEq(t, nil, srv.Wait(waitCtx))
+	assertEq(t, nil, db.Close())
+
+	v, _ := st.Get("late")
+	assertEq(t, "write", v)
+}
@@ -1,19 +1,28 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"os"
-	"strings"
-	"time"
+	"reflect"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 type App struct {
-	Debug    bool
-	Channels []*Channel
+	Debug       bool        `yaml:",omitempty"`
+	Store       string      `yaml:",omitempty"`
+	Workers     Workers     `yaml:",omitempty"`
+	Record      Record      `yaml:",omitempty"`
+	Definitions Definitions `yaml:",omitempty"`
+	Channels    []*Channel  `yaml:",omitempty"`
+
+	lines   map[string]int
+	origins map[string]string
 }
 
+const defaultStorePath = "./store.log"
+
 func Read(path string) (*App, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -22,160 +31,123 @@ func Read(path string) (*App, error) {
 
 	defer f.Close()
 
-	var cfg App
+	var root yaml.Node
 
-	err = yaml.NewDecoder(f).Decode(&cfg)
+	err = yaml.NewDecoder(f).Decode(&root)
 	if err != nil {
 		return nil, err
 	}
 
-	return &cfg, nil
-}
+	var cfg App
 
-type Channel struct {
-	Name string
-	Chat *Chat
-}
+	err = root.Decode(&cfg)
+	if err != nil {
+		return nil, err
+	}
 
-type Chat struct {
-	Rewards     []*Trigger
-	Commands    []*Trigger
-	Middlewares []*Feature
-}
+	cfg.lines = make(map[string]int)
+	indexLines(&root, "", cfg.lines)
 
-type Trigger struct {
-	Key         string
-	Action      *Feature
-	Middlewares []*Feature
-}
+	var errs Errors
 
-type Feature struct {
-	Type     string
-	Settings Settings
-}
-
-type Settings map[string]interface{}
+	checkKeys(&root, reflect.TypeOf(cfg), "", &errs)
 
-func (s Settings) MustString(name string) string {
-	str, ok := s.String(name)
-	if !ok {
-		log.Fatalf("value not found: %v\n", name)
-	}
+	err = cfg.resolve()
 
-	return str
-}
-
-func (s Settings) String(name string) (string, bool) {
-	v, ok := s[name]
-	if !ok {
-		return "", false
-	}
+	var resolveErrs Errors
 
-	str, ok := v.(string)
-	if !ok {
-		log.Fatalf("value has incorrect type: %t\n", v)
+	switch {
+	case errors.As(err, &resolveErrs):
+		errs = append(errs, resolveErrs...)
+	case err != nil:
+		errs = append(errs, err)
 	}
 
-	return str, true
-}
-
-func (s Settings) StringFromSet(name string, set []string) (string, bool) {
-	str, ok := s.String(name)
-	if !ok {
-		return "", false
+	if err = errs.Err(); err != nil {
+		return nil, err
 	}
 
-	for _, e := range set {
-		if str == e {
-			return str, true
-		}
+	if cfg.Store == "" {
+		cfg.Store = defaultStorePath
 	}
 
-	log.Fatalf("unexpected value: %v, expected values: %v\n",
-		str, strings.Join(set, ", "))
-
-	return "", false
+	return &cfg, nil
 }
 
-func (s Settings) StringFromSetWithDefault(name string, set []string, d string) string {
-	str, ok := s.StringFromSet(name, set)
-	if !ok {
-		return d
-	}
-
-	return str
+// Workers configure handling of messages, zero values mean defaults.
+type Workers struct {
+	Count   int    `yaml:",omitempty"`
+	Queue   int    `yaml:",omitempty"`
+	Timeout string `yaml:",omitempty"`
+	Lane    string `yaml:",omitempty"` // channel or user
 }
 
-func (s Settings) MustDuration(name string) time.Duration {
-	str := s.MustString(name)
-
-	d, err := time.ParseDuration(str)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	return d
+// Record configures recording of raw chat lines, lines are
+// not recorded if Path is empty.
+type Record struct {
+	Path     string `yaml:",omitempty"`
+	MaxSize  int    `yaml:"maxSize,omitempty"`  // megabytes
+	MaxFiles int    `yaml:"maxFiles,omitempty"` // rotated files kept
 }
 
-func (s Settings) Int(name string) (int, bool) {
-	v, ok := s[name]
-	if !ok {
-		return 0, false
-	}
+type Channel struct {
+	Name string
+	// ReadOnly channel is only observed, handlers are run
+	// but their messages are logged instead of being sent.
+	ReadOnly bool  `yaml:"readOnly,omitempty"`
+	Chat     *Chat `yaml:",omitempty"`
+}
 
-	n, ok := v.(int)
-	if !ok {
-		log.Fatalf("value has incorrect type: %t\n", v)
-	}
+type Chat struct {
+	Use         string     `yaml:",omitempty"` // name of chat definition
+	Rewards     []*Trigger `yaml:",omitempty"`
+	Commands    []*Trigger `yaml:",omitempty"`
+	Events      []*Trigger `yaml:",omitempty"`
+	Middlewares []*Feature `yaml:",omitempty"`
+	Timers      []*Timer   `yaml:",omitempty"`
+}
 
-	return n, true
+// Timer posts messages in rotation, either Interval or Cron
+// has to be set.
+type Timer struct {
+	Interval string `yaml:",omitempty"`
+	Cron     string `yaml:",omitempty"`
+	MinLines int    `yaml:"minLines,omitempty"`
+	Messages []string
 }
 
-func (s Settings) MustInt(name string) int {
-	n, ok := s.Int(name)
-	if !ok {
-		log.Fatalf("value not found: %v\n", name)
-	}
+type Trigger struct {
+	Key         string
+	Action      *Feature
+	Middlewares []*Feature `yaml:",omitempty"`
+}
 
-	return n
+type Feature struct {
+	Use      string `yaml:",omitempty"` // name of middleware or action definition
+	Type     string
+	Settings Settings `yaml:",omitempty"`
 }
 
-// Bool returns false if value is not found
-func (s Settings) Bool(name string) bool {
-	v, ok := s[name]
-	if !ok {
-		return false
-	}
+type Settings map[string]interface{}
 
-	b, ok := v.(bool)
-	if !ok {
-		log.Fatalf("value has incorrect type: %t\n", v)
-	}
+var ErrNotFound = errors.New("value not found")
 
-	return b
+func typeError(v interface{}) error {
+	return fmt.Errorf("value has incorrect type: %T", v)
 }
 
-func (s Settings) Strings(name string) []string {
+// RequiredString is used to read settings which define what
+// struct the rest of settings is decoded to.
+func (s Settings) RequiredString(name string) (string, error) {
 	v, ok := s[name]
 	if !ok {
-		return nil
+		return "", Errorf("settings."+name, "%w", ErrNotFound)
 	}
 
-	arr, ok := v.([]interface{})
+	str, ok := v.(string)
 	if !ok {
-		log.Fatalf("value has incorrect type: %t", v)
-	}
-
-	a := make([]string, len(arr))
-
-	for i := range arr {
-		str, ok := arr[i].(string)
-		if !ok {
-			log.Fatalf("value has incorrect type: %t", arr[i])
-		}
-
-		a[i] = str
+		return "", Errorf("settings."+name, "%w", typeError(v))
 	}
 
-	return a
+	return str, nil
 }
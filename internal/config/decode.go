@@ -0,0 +1,329 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Validator is implemented by settings structs which have rules
+// not expressible by tags. Paths of returned PathErrors are relative
+// to settings, e.g. "duration".
+type Validator interface {
+	Validate() error
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Decode sets exported fields of structs pointed by targets. Name
+// of setting is taken from `config` tag or field name with lower
+// first letter, fields of embedded structs are treated as fields
+// of the outer one. Supported tags:
+//
+//	config:"name,required"  setting name and whether it has to be set
+//	default:"value"         value used when setting is missing
+//	oneof:"a b c"           allowed values
+//	min:"value"             lower bound of numbers and durations
+//
+// Settings not matched by any field are rejected. All found errors
+// are returned as Errors.
+func (s Settings) Decode(targets ...interface{}) error {
+	var (
+		errs  Errors
+		known = make(map[string]bool)
+	)
+
+	for _, target := range targets {
+		v := reflect.ValueOf(target)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			panic(fmt.Sprintf("config: decode target must be pointer to struct, got %T", target))
+		}
+
+		n := len(errs)
+
+		s.decodeStruct(v.Elem(), known, &errs)
+
+		if len(errs) > n {
+			continue
+		}
+
+		if vd, ok := target.(Validator); ok {
+			if err := vd.Validate(); err != nil {
+				errs = append(errs, inSettings(err))
+			}
+		}
+	}
+
+	var unknown []string
+
+	for name := range s {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	sort.Strings(unknown)
+
+	for _, name := range unknown {
+		errs = append(errs, Errorf("settings."+name, "unknown setting"))
+	}
+
+	return errs.Err()
+}
+
+func inSettings(err error) error {
+	var pe *PathError
+	if errors.As(err, &pe) {
+		return Errorf(joinPath("settings", pe.Path), "%w", pe.Err)
+	}
+
+	return &PathError{Path: "settings", Err: err}
+}
+
+func (s Settings) decodeStruct(v reflect.Value, known map[string]bool, errs *Errors) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			s.decodeStruct(v.Field(i), known, errs)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, required := fieldName(f)
+		known[name] = true
+
+		if err := s.decodeField(v.Field(i), f, name, required); err != nil {
+			*errs = append(*errs, Errorf("settings."+name, "%w", err))
+		}
+	}
+}
+
+func fieldName(f reflect.StructField) (string, bool) {
+	tag := strings.Split(f.Tag.Get("config"), ",")
+
+	name := tag[0]
+	if name == "" {
+		r, n := utf8.DecodeRuneInString(f.Name)
+		name = string(unicode.ToLower(r)) + f.Name[n:]
+	}
+
+	for _, opt := range tag[1:] {
+		if opt == "required" {
+			return name, true
+		}
+	}
+
+	return name, false
+}
+
+func (s Settings) decodeField(v reflect.Value, f reflect.StructField, name string, required bool) error {
+	raw, ok := s[name]
+
+	switch {
+	case ok && raw != nil:
+		if err := setValue(v, raw); err != nil {
+			return err
+		}
+	case required:
+		return ErrNotFound
+	default:
+		d, ok := f.Tag.Lookup("default")
+		if !ok {
+			return nil
+		}
+
+		if err := setValue(v, d); err != nil {
+			panic(fmt.Sprintf("config: bad default of %s: %v", f.Name, err))
+		}
+	}
+
+	if set, ok := f.Tag.Lookup("oneof"); ok {
+		if err := checkOneOf(v, strings.Fields(set)); err != nil {
+			return err
+		}
+	}
+
+	if m, ok := f.Tag.Lookup("min"); ok {
+		bound := reflect.New(v.Type()).Elem()
+
+		if err := setValue(bound, m); err != nil {
+			panic(fmt.Sprintf("config: bad min of %s: %v", f.Name, err))
+		}
+
+		if less(v, bound) {
+			return fmt.Errorf("value must be at least %s", m)
+		}
+	}
+
+	return nil
+}
+
+// setValue converts raw value decoded from YAML or taken
+// from tag to the type of v.
+func setValue(v reflect.Value, raw interface{}) error {
+	if v.Type() == durationType {
+		str, ok := raw.(string)
+		if !ok {
+			return typeError(raw)
+		}
+
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		str, ok := raw.(string)
+		if !ok {
+			return typeError(raw)
+		}
+
+		v.SetString(str)
+	case reflect.Bool:
+		switch b := raw.(type) {
+		case bool:
+			v.SetBool(b)
+		case string:
+			parsed, err := strconv.ParseBool(b)
+			if err != nil {
+				return typeError(raw)
+			}
+
+			v.SetBool(parsed)
+		default:
+			return typeError(raw)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt(raw)
+		if err != nil {
+			return err
+		}
+
+		if v.OverflowInt(n) {
+			return fmt.Errorf("value is out of range: %d", n)
+		}
+
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		x, err := toFloat(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(x)
+	case reflect.Slice:
+		arr, ok := raw.([]interface{})
+		if !ok {
+			return typeError(raw)
+		}
+
+		sl := reflect.MakeSlice(v.Type(), len(arr), len(arr))
+
+		for i := range arr {
+			if err := setValue(sl.Index(i), arr[i]); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+
+		v.Set(sl)
+	default:
+		panic(fmt.Sprintf("config: unsupported settings field type: %v", v.Type()))
+	}
+
+	return nil
+}
+
+func toInt(raw interface{}) (int64, error) {
+	switch n := raw.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, fmt.Errorf("value is out of range: %d", n)
+		}
+
+		return int64(n), nil
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, fmt.Errorf("value must be integer: %v", n)
+		}
+
+		return int64(n), nil
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		if err != nil {
+			return 0, typeError(raw)
+		}
+
+		return i, nil
+	}
+
+	return 0, typeError(raw)
+}
+
+func toFloat(raw interface{}) (float64, error) {
+	switch x := raw.(type) {
+	case float64:
+		return x, nil
+	case int:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		if err != nil {
+			return 0, typeError(raw)
+		}
+
+		return f, nil
+	}
+
+	return 0, typeError(raw)
+}
+
+func checkOneOf(v reflect.Value, set []string) error {
+	str := fmt.Sprint(v.Interface())
+
+	for _, e := range set {
+		if str == e {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unexpected value: %v, expected values: %v",
+		str, strings.Join(set, ", "))
+}
+
+func less(v, bound reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() < bound.Int()
+	case reflect.Float32, reflect.Float64:
+		return v.Float() < bound.Float()
+	}
+
+	panic(fmt.Sprintf("config: min is not supported for %v", v.Type()))
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func assertEq(t *testing.T, expected, actual interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nexpected: '%v',\nactual: '%v'", expected, actual)
+	}
+}
+
+type testRoles struct {
+	Mod bool `config:"allowMod"`
+}
+
+type testSettings struct {
+	testRoles
+
+	Name   string        `config:"name,required"`
+	Op     string        `config:"op" default:"show" oneof:"show inc"`
+	Step   int64         `default:"1" min:"1"`
+	Period time.Duration `config:"period"`
+	Words  []string      `config:"words"`
+
+	hidden string
+}
+
+func TestDecode(t *testing.T) {
+	var opts testSettings
+
+	err := Settings{
+		"name":     "deaths",
+		"allowMod": true,
+		"step":     2.0,
+		"period":   "5m",
+		"words":    []interface{}{"a", "b"},
+	}.Decode(&opts)
+
+	assertEq(t, nil, err)
+	assertEq(t, testSettings{
+		testRoles: testRoles{Mod: true},
+		Name:      "deaths",
+		Op:        "show",
+		Step:      2,
+		Period:    5 * time.Minute,
+		Words:     []string{"a", "b"},
+	}, opts)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var opts testSettings
+
+	err := Settings{
+		"op":     "dec",
+		"step":   0,
+		"period": 5,
+		"words":  []interface{}{"a", 1},
+		"typo":   "value",
+		"hidden": "value",
+	}.Decode(&opts)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got: %v", err)
+	}
+
+	var paths []string
+	for _, err := range errs {
+		var pe *PathError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expected PathError, got: %v", err)
+		}
+
+		paths = append(paths, pe.Path)
+	}
+
+	assertEq(t, []string{
+		"settings.name",
+		"settings.op",
+		"settings.step",
+		"settings.period",
+		"settings.words",
+		"settings.hidden",
+		"settings.typo",
+	}, paths)
+	assertEq(t, true, errors.Is(errs[0], ErrNotFound))
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Definitions are named middlewares, actions and chats which
+// are referenced by "use" instead of being repeated in channels.
+//
+// Feature that uses definition gets its type and settings, own
+// settings of the feature override ones of definition. Chat that
+// uses definition gets its middlewares and timers before own ones,
+// its triggers are replaced by own triggers with the same key.
+// Definitions can use other definitions of the same kind.
+type Definitions struct {
+	Middlewares map[string]*Feature
+	Actions     map[string]*Feature
+	Chats       map[string]*Chat
+}
+
+const (
+	kindMiddlewares = "middlewares"
+	kindActions     = "actions"
+	kindChats       = "chats"
+)
+
+type resolver struct {
+	cfg      *App
+	visiting map[string]bool
+	errs     Errors
+}
+
+// resolve replaces references to definitions with their content.
+func (cfg *App) resolve() error {
+	cfg.origins = make(map[string]string)
+
+	r := resolver{
+		cfg:      cfg,
+		visiting: make(map[string]bool),
+	}
+
+	defs := &cfg.Definitions
+
+	for _, name := range sortedKeys(defs.Middlewares) {
+		r.feature(kindMiddlewares, defPath(kindMiddlewares, name), defs.Middlewares[name])
+	}
+
+	for _, name := range sortedKeys(defs.Actions) {
+		r.feature(kindActions, defPath(kindActions, name), defs.Actions[name])
+	}
+
+	chatNames := make([]string, 0, len(defs.Chats))
+	for name := range defs.Chats {
+		chatNames = append(chatNames, name)
+	}
+
+	sort.Strings(chatNames)
+
+	for _, name := range chatNames {
+		r.chat(defPath(kindChats, name), defs.Chats[name])
+	}
+
+	for i, ch := range cfg.Channels {
+		if ch.Chat != nil {
+			r.chat(fmt.Sprintf("channels[%d].chat", i), ch.Chat)
+		}
+	}
+
+	return r.errs.Err()
+}
+
+func defPath(kind, name string) string {
+	return "definitions." + kind + "." + name
+}
+
+func sortedKeys(m map[string]*Feature) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (r *resolver) fail(path string, err error) {
+	r.errs = append(r.errs, r.cfg.Locate(path, err))
+}
+
+// enter marks path as being resolved, false is returned
+// if definition src used by path is being resolved already.
+func (r *resolver) enter(path, src string) bool {
+	if r.visiting[src] || src == path {
+		r.fail(path+".use", fmt.Errorf("definition uses itself: %s", src))
+		return false
+	}
+
+	r.visiting[path] = true
+
+	return true
+}
+
+func (r *resolver) feature(kind, path string, f *Feature) {
+	if f == nil || f.Use == "" {
+		return
+	}
+
+	// reference is resolved once, even if it is broken
+	use := f.Use
+	f.Use = ""
+
+	var defs map[string]*Feature
+	if kind == kindMiddlewares {
+		defs = r.cfg.Definitions.Middlewares
+	} else {
+		defs = r.cfg.Definitions.Actions
+	}
+
+	src := defPath(kind, use)
+
+	base, ok := defs[use]
+	if !ok || base == nil {
+		r.fail(path+".use", fmt.Errorf("definition not found: %s", src))
+		return
+	}
+
+	if !r.enter(path, src) {
+		return
+	}
+
+	r.feature(kind, src, base)
+
+	delete(r.visiting, path)
+
+	if f.Type != "" && f.Type != base.Type {
+		r.fail(path+".type", fmt.Errorf("type of %s can not be changed", src))
+		return
+	}
+
+	settings := make(Settings, len(base.Settings)+len(f.Settings))
+
+	for k, v := range base.Settings {
+		settings[k] = v
+		r.cfg.origins[path+".settings."+k] = r.cfg.source(src + ".settings." + k)
+	}
+
+	for k, v := range f.Settings {
+		settings[k] = v
+		delete(r.cfg.origins, path+".settings."+k)
+	}
+
+	if f.Type == "" {
+		r.cfg.origins[path+".type"] = r.cfg.source(src + ".type")
+	}
+
+	f.Type = base.Type
+	f.Settings = settings
+}
+
+func (r *resolver) chat(path string, c *Chat) {
+	if c.Use != "" {
+		r.inherit(path, c)
+	}
+
+	r.triggers(path+".rewards", c.Rewards)
+	r.triggers(path+".commands", c.Commands)
+	r.triggers(path+".events", c.Events)
+
+	for i, f := range c.Middlewares {
+		r.feature(kindMiddlewares, fmt.Sprintf("%s.middlewares[%d]", path, i), f)
+	}
+}
+
+func (r *resolver) triggers(path string, ts []*Trigger) {
+	for i, t := range ts {
+		if t == nil {
+			continue
+		}
+
+		p := fmt.Sprintf("%s[%d]", path, i)
+
+		r.feature(kindActions, p+".action", t.Action)
+
+		for j, f := range t.Middlewares {
+			r.feature(kindMiddlewares, fmt.Sprintf("%s.middlewares[%d]", p, j), f)
+		}
+	}
+}
+
+// inherit merges content of chat definition used by c into c.
+func (r *resolver) inherit(path string, c *Chat) {
+	use := c.Use
+	c.Use = ""
+
+	src := defPath(kindChats, use)
+
+	base, ok := r.cfg.Definitions.Chats[use]
+	if !ok || base == nil {
+		r.fail(path+".use", fmt.Errorf("definition not found: %s", src))
+		return
+	}
+
+	if !r.enter(path, src) {
+		return
+	}
+
+	r.chat(src, base)
+
+	delete(r.visiting, path)
+
+	// origins of merged elements are collected first, so own elements
+	// moved to other index are not confused with inherited ones
+	m := merger{cfg: r.cfg, origins: make(map[string]string)}
+
+	c.Rewards = mergeTriggers(&m, path+".rewards", src+".rewards", base.Rewards, c.Rewards)
+	c.Commands = mergeTriggers(&m, path+".commands", src+".commands", base.Commands, c.Commands)
+	c.Events = mergeTriggers(&m, path+".events", src+".events", base.Events, c.Events)
+
+	middlewares := make([]*Feature, 0, len(base.Middlewares)+len(c.Middlewares))
+	middlewares = append(middlewares, base.Middlewares...)
+	middlewares = append(middlewares, c.Middlewares...)
+
+	m.moved(path+".middlewares", src+".middlewares", len(base.Middlewares), len(c.Middlewares))
+	c.Middlewares = middlewares
+
+	timers := make([]*Timer, 0, len(base.Timers)+len(c.Timers))
+	timers = append(timers, base.Timers...)
+	timers = append(timers, c.Timers...)
+
+	m.moved(path+".timers", src+".timers", len(base.Timers), len(c.Timers))
+	c.Timers = timers
+
+	for dst, src := range m.origins {
+		r.cfg.origins[dst] = src
+	}
+}
+
+type merger struct {
+	cfg     *App
+	origins map[string]string
+}
+
+// inherited records origin of element and origins of its values
+// which are copied from other definitions.
+func (m *merger) inherited(dst, src string) {
+	m.origins[dst] = m.cfg.source(src)
+
+	for p, origin := range m.cfg.origins {
+		if rest := strings.TrimPrefix(p, src); rest != p && (rest[0] == '.' || rest[0] == '[') {
+			m.origins[dst+rest] = origin
+		}
+	}
+}
+
+func (m *merger) own(dst, src string) {
+	if dst != src {
+		m.origins[dst] = src
+	}
+}
+
+// moved records origins of list made of n inherited elements
+// followed by k own ones.
+func (m *merger) moved(path, src string, n, k int) {
+	for i := 0; i < n; i++ {
+		m.inherited(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("%s[%d]", src, i))
+	}
+
+	for j := 0; j < k; j++ {
+		m.own(fmt.Sprintf("%s[%d]", path, n+j), fmt.Sprintf("%s[%d]", path, j))
+	}
+}
+
+func mergeTriggers(m *merger, path, src string, base, own []*Trigger) []*Trigger {
+	if len(base) == 0 {
+		return own
+	}
+
+	keys := make(map[string]bool, len(own))
+	for _, t := range own {
+		if t != nil {
+			keys[t.Key] = true
+		}
+	}
+
+	merged := make([]*Trigger, 0, len(base)+len(own))
+
+	for i, t := range base {
+		if t != nil && keys[t.Key] {
+			continue
+		}
+
+		m.inherited(fmt.Sprintf("%s[%d]", path, len(merged)), fmt.Sprintf("%s[%d]", src, i))
+		merged = append(merged, t)
+	}
+
+	for j, t := range own {
+		m.own(fmt.Sprintf("%s[%d]", path, len(merged)), fmt.Sprintf("%s[%d]", path, j))
+		merged = append(merged, t)
+	}
+
+	return merged
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const definitionsConfig = `definitions:
+  middlewares:
+    noLinks:
+      type: filter
+      settings:
+        type: blockLinks
+        penalty: deleteMsg
+    noLinksTimeout:
+      use: noLinks
+      settings:
+        penalty: timeout
+        duration: 10m
+  chats:
+    base:
+      commands:
+        - key: hello
+          action:
+            type: print
+            settings:
+              text: hello
+        - key: bye
+          action:
+            type: print
+            settings:
+              text: bye
+      middlewares:
+        - use: noLinks
+channels:
+  - name: first
+    chat:
+      use: base
+      commands:
+        - key: bye
+          action:
+            type: print
+            settings:
+              text: see you
+      middlewares:
+        - use: noLinksTimeout
+          settings:
+            reply: no links
+`
+
+func readConfig(t *testing.T, text string) (*App, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	err := os.WriteFile(path, []byte(text), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Read(path)
+}
+
+func TestDefinitions(t *testing.T) {
+	cfg, err := readConfig(t, definitionsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chat := cfg.Channels[0].Chat
+
+	var keys, texts []string
+	for _, c := range chat.Commands {
+		keys = append(keys, c.Key)
+		texts = append(texts, c.Action.Settings["text"].(string))
+	}
+
+	assertEq(t, []string{"hello", "bye"}, keys)
+	assertEq(t, []string{"hello", "see you"}, texts)
+
+	assertEq(t, 2, len(chat.Middlewares))
+	assertEq(t, "filter", chat.Middlewares[1].Type)
+	assertEq(t, Settings{
+		"type":     "blockLinks",
+		"penalty":  "timeout",
+		"duration": "10m",
+		"reply":    "no links",
+	}, chat.Middlewares[1].Settings)
+
+	// errors of inherited values point to definitions
+	assertEq(t, 7, cfg.line("channels[0].chat.middlewares[0].settings.penalty"))
+	assertEq(t, 12, cfg.line("channels[0].chat.middlewares[1].settings.duration"))
+	assertEq(t, 41, cfg.line("channels[0].chat.middlewares[1].settings.reply"))
+	assertEq(t, 20, cfg.line("channels[0].chat.commands[0].action.settings.text"))
+	assertEq(t, 37, cfg.line("channels[0].chat.commands[1].action.settings.text"))
+}
+
+func TestDefinitionErrors(t *testing.T) {
+	_, err := readConfig(t, `definitions:
+  middlewares:
+    loop:
+      use: loop
+channels:
+  - name: first
+    chat:
+      use: missing
+`)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got: %v", err)
+	}
+
+	var lines []int
+	for _, err := range errs {
+		var e *Error
+		if errors.As(err, &e) {
+			lines = append(lines, e.Line)
+		}
+	}
+
+	assertEq(t, []int{4, 8}, lines)
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PathError is an error of value located by path relative to
+// the object being validated, e.g. "settings.period".
+type PathError struct {
+	Path string
+	Err  error
+}
+
+func Errorf(path, format string, args ...interface{}) error {
+	return &PathError{
+		Path: path,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// Error is an error located by full YAML path, e.g.
+// "channels[1].chat.commands[3].action.settings.period".
+// Line is zero if position is unknown.
+type Error struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Path, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects all errors of config to report them at once.
+type Errors []error
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Err returns nil if there are no errors.
+func (errs Errors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// Locate makes Error of err found at path, path of PathError
+// is appended to the given one.
+func (cfg *App) Locate(path string, err error) error {
+	var pe *PathError
+	if errors.As(err, &pe) {
+		path = joinPath(path, pe.Path)
+		err = pe.Err
+	}
+
+	return &Error{
+		Path: path,
+		Line: cfg.line(path),
+		Err:  err,
+	}
+}
+
+func joinPath(path, sub string) string {
+	if path == "" || strings.HasPrefix(sub, "[") {
+		return path + sub
+	}
+
+	return path + "." + sub
+}
+
+// line returns line of the value or of its closest parent
+// if value itself is missing. Values copied from definitions
+// are looked up at their origins.
+func (cfg *App) line(path string) int {
+	return cfg.sourceLine(cfg.source(path))
+}
+
+// source translates path of value to its path in config file.
+func (cfg *App) source(path string) string {
+	for p := path; p != ""; p = parent(p) {
+		if src, ok := cfg.origins[p]; ok {
+			return src + path[len(p):]
+		}
+	}
+
+	return path
+}
+
+func (cfg *App) sourceLine(path string) int {
+	for p := path; p != ""; p = parent(p) {
+		if n, ok := cfg.lines[p]; ok {
+			return n
+		}
+	}
+
+	return 0
+}
+
+func parent(path string) string {
+	off := strings.LastIndexAny(path, ".[")
+	if off == -1 {
+		return ""
+	}
+
+	return path[:off]
+}
+
+func indexLines(n *yaml.Node, path string, lines map[string]int) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			indexLines(c, path, lines)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+
+			p := joinPath(path, k.Value)
+			lines[p] = k.Line
+
+			indexLines(v, p, lines)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			lines[p] = c.Line
+
+			indexLines(c, p, lines)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown key")
+	ErrEmptyItem  = errors.New("empty list item")
+)
+
+var settingsType = reflect.TypeOf(Settings(nil))
+
+// checkKeys reports keys of mappings in n that have no matching field
+// in t, so typos are not silently ignored, and empty items of lists
+// of objects. Settings are not checked here, they are checked
+// by Decode of features.
+func checkKeys(n *yaml.Node, t reflect.Type, path string, errs *Errors) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == settingsType {
+		return
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			checkKeys(c, t, path, errs)
+		}
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := yamlFields(t)
+
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				p := joinPath(path, k.Value)
+
+				ft, ok := fields[k.Value]
+				if !ok {
+					*errs = append(*errs, &Error{Path: p, Line: k.Line, Err: ErrUnknownKey})
+					continue
+				}
+
+				checkKeys(v, ft, p, errs)
+			}
+		case reflect.Map:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				checkKeys(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value), errs)
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice {
+			return
+		}
+
+		for i, c := range n.Content {
+			p := fmt.Sprintf("%s[%d]", path, i)
+
+			if c.Tag == "!!null" && t.Elem().Kind() == reflect.Ptr {
+				*errs = append(*errs, &Error{Path: p, Line: c.Line, Err: ErrEmptyItem})
+				continue
+			}
+
+			checkKeys(c, t.Elem(), p, errs)
+		}
+	}
+}
+
+// yamlFields returns types of exported fields by their yaml keys.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		fields[name] = f.Type
+	}
+
+	return fields
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+const typoConfig = `definitions:
+  actions:
+    hi:
+      type: print
+      setings:
+        text: hi
+channels:
+  - name: first
+    chat:
+      comands:
+        - key: hi
+          action:
+            use: hi
+      middlewares:
+        -
+        - type: filter
+          settings:
+            anything: goes
+`
+
+func TestUnknownKeys(t *testing.T) {
+	_, err := readConfig(t, typoConfig)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected config errors, got: %v", err)
+	}
+
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+
+	// settings are checked by features, not here
+	assertEq(t, []string{
+		"line 5: definitions.actions.hi.setings: unknown key",
+		"line 10: channels[0].chat.comands: unknown key",
+		"line 15: channels[0].chat.middlewares[0]: empty list item",
+	}, msgs)
+
+	assertEq(t, true, errors.Is(errs[0], ErrUnknownKey))
+}
@@ -1,49 +1,203 @@
 package creds
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
-var storage map[string]string
-
 const (
-	keyTwitchUser = "twitchuser"
-	keyTwitchPass = "twitchpass"
-	keyRiotAPIKey = "riotapikey"
+	KeyTwitchUser         = "twitchuser"
+	KeyTwitchPass         = "twitchpass"
+	KeyTwitchClientID     = "twitchclientid"
+	KeyTwitchClientSecret = "twitchclientsecret"
+	KeyTwitchRefreshToken = "twitchrefreshtoken"
+	KeyRiotAPIKey         = "riotapikey"
 )
 
-func Load(path string) error {
+var keys = []string{
+	KeyTwitchUser,
+	KeyTwitchPass,
+	KeyTwitchClientID,
+	KeyTwitchClientSecret,
+	KeyTwitchRefreshToken,
+	KeyRiotAPIKey,
+}
+
+// Keys returns all known cred keys.
+func Keys() []string {
+	return append([]string(nil), keys...)
+}
+
+// EnvPrefix is prefix of environment variables with creds,
+// e.g. MICROBOT_TWITCHPASS.
+const EnvPrefix = "MICROBOT_"
+
+var ErrNotFound = errors.New("cred value not found")
+
+// Options are sources of creds. Value of cred is taken from the first
+// source that has it:
+//
+//  1. environment variable MICROBOT_<KEY>,
+//  2. file named by environment variable MICROBOT_<KEY>_FILE,
+//  3. file <key> in SecretsDir,
+//  4. YAML file at File.
+type Options struct {
+	File         string
+	FileOptional bool // missing File is not an error
+	SecretsDir   string
+
+	// Getenv is used to read environment, os.Getenv if nil.
+	Getenv func(string) string
+}
+
+// Creds are values resolved from sources at load time.
+type Creds struct {
+	values map[string]string
+}
+
+func Load(opts Options) (*Creds, error) {
+	getenv := opts.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+
+	var file map[string]string
+
+	if opts.File != "" {
+		var err error
+
+		file, err = readFile(opts.File)
+		if err != nil && !(opts.FileOptional && errors.Is(err, os.ErrNotExist)) {
+			return nil, err
+		}
+	}
+
+	c := &Creds{values: make(map[string]string)}
+
+	for _, key := range keys {
+		v, ok, err := lookup(key, opts, getenv, file)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			c.values[key] = v
+		}
+	}
+
+	return c, nil
+}
+
+func readFile(path string) (map[string]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer f.Close()
 
-	err = yaml.NewDecoder(f).Decode(&storage)
-	return err
+	var m map[string]string
+
+	err = yaml.NewDecoder(f).Decode(&m)
+	if err != nil {
+		return nil, fmt.Errorf("creds file %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+func lookup(
+	key string,
+	opts Options,
+	getenv func(string) string,
+	file map[string]string,
+) (string, bool, error) {
+	name := envName(key)
+
+	if v := getenv(name); v != "" {
+		return v, true, nil
+	}
+
+	if path := getenv(name + "_FILE"); path != "" {
+		v, err := readSecret(path)
+		return v, err == nil, err
+	}
+
+	if opts.SecretsDir != "" {
+		v, err := readSecret(filepath.Join(opts.SecretsDir, key))
+		switch {
+		case err == nil:
+			return v, true, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return "", false, err
+		}
+	}
+
+	v, ok := file[key]
+
+	return v, ok && v != "", nil
 }
 
-func getValue(key string) string {
-	v, ok := storage[key]
+// readSecret reads value from file, trailing newline
+// which is usually left by editors is trimmed.
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Has reports whether cred is set.
+func (c *Creds) Has(key string) bool {
+	_, ok := c.values[key]
+	return ok
+}
+
+// Get returns value of cred, error describes where
+// the value is expected to be set.
+func (c *Creds) Get(key string) (string, error) {
+	v, ok := c.values[key]
 	if !ok {
-		log.Fatalf("cred value not found: %s\n", key)
+		return "", fmt.Errorf("%w: %s (set %s, %s_FILE or %s in creds file)",
+			ErrNotFound, key, envName(key), envName(key), key)
 	}
 
-	return v
+	return v, nil
 }
 
-func TwitchUser() string {
-	return getValue(keyTwitchUser)
+// Require checks that all keys are set, all missing
+// keys are reported.
+func (c *Creds) Require(keys ...string) error {
+	var msgs []string
+
+	for _, key := range keys {
+		if _, err := c.Get(key); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(msgs, "\n"))
 }
 
-func TwitchPass() string {
-	return getValue(keyTwitchPass)
+func (c *Creds) TwitchUser() string {
+	return c.values[KeyTwitchUser]
 }
 
-func RiotAPIKey() string {
-	return getValue(keyRiotAPIKey)
+func (c *Creds) TwitchPass() string {
+	return c.values[KeyTwitchPass]
 }
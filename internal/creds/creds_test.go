@@ -0,0 +1,90 @@
+package creds
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, text string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(text), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "creds.yml")
+	writeFile(t, file, "twitchuser: file-user\ntwitchpass: file-pass\n")
+
+	secrets := filepath.Join(dir, "secrets")
+	if err := os.Mkdir(secrets, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Join(secrets, "twitchpass"), "secret-pass\n")
+	writeFile(t, filepath.Join(dir, "pass"), "env-file-pass\n")
+
+	env := map[string]string{
+		"MICROBOT_TWITCHUSER": "env-user",
+	}
+
+	opts := Options{
+		File:       file,
+		SecretsDir: secrets,
+		Getenv: func(name string) string {
+			return env[name]
+		},
+	}
+
+	c, err := Load(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.TwitchUser() != "env-user" || c.TwitchPass() != "secret-pass" {
+		t.Errorf("unexpected creds: %s, %s", c.TwitchUser(), c.TwitchPass())
+	}
+
+	env["MICROBOT_TWITCHPASS_FILE"] = filepath.Join(dir, "pass")
+
+	c, err = Load(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.TwitchPass() != "env-file-pass" {
+		t.Errorf("unexpected pass: %s", c.TwitchPass())
+	}
+
+	if _, err = c.Get(KeyRiotAPIKey); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	opts := Options{
+		File:   filepath.Join(t.TempDir(), "creds.yml"),
+		Getenv: func(string) string { return "" },
+	}
+
+	if _, err := Load(opts); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got: %v", err)
+	}
+
+	opts.FileOptional = true
+
+	c, err := Load(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Require(KeyTwitchUser, KeyTwitchPass); err == nil {
+		t.Error("expected error of missing creds")
+	}
+}
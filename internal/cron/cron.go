@@ -0,0 +1,163 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is parsed cron expression of five fields: minute, hour,
+// day of month, month and day of week. Fields support "*", ranges
+// "a-b", steps "*/n", "a-b/n" and lists "a,b,c".
+type Schedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	anyDOM bool
+	anyDOW bool
+}
+
+type bounds struct {
+	min, max int
+}
+
+var (
+	minuteBounds = bounds{0, 59}
+	hourBounds   = bounds{0, 23}
+	domBounds    = bounds{1, 31}
+	monthBounds  = bounds{1, 12}
+	dowBounds    = bounds{0, 6}
+)
+
+func Parse(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression, got %d", len(fields))
+	}
+
+	var (
+		s   Schedule
+		err error
+	)
+
+	if s.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+
+	// like in vixie cron, days must match both fields
+	// if one of them starts with star, e.g. */2
+	s.anyDOM = strings.HasPrefix(fields[2], "*")
+	s.anyDOW = strings.HasPrefix(fields[4], "*")
+
+	return &s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+
+		if off := strings.IndexByte(part, '/'); off != -1 {
+			var err error
+
+			rng = part[:off]
+
+			step, err = strconv.Atoi(part[off+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in cron field: %s", part)
+			}
+		}
+
+		lo, hi := b.min, b.max
+
+		if rng != "*" {
+			var err error
+
+			if off := strings.IndexByte(rng, '-'); off != -1 {
+				lo, err = strconv.Atoi(rng[:off])
+				if err == nil {
+					hi, err = strconv.Atoi(rng[off+1:])
+				}
+			} else {
+				lo, err = strconv.Atoi(rng)
+				hi = lo
+			}
+
+			if err != nil {
+				return 0, fmt.Errorf("invalid cron field: %s", part)
+			}
+		}
+
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("cron field out of range: %s", part)
+		}
+
+		for i := lo; i <= hi; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func has(bits uint64, i int) bool {
+	return bits&(1<<uint(i)) != 0
+}
+
+// dayMatches follows cron convention: when both day of month and
+// day of week are restricted, either of them has to match.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+
+	if s.anyDOM || s.anyDOW {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// maxYears limits search of impossible schedules like "0 0 31 2 *".
+const maxYears = 5
+
+// Next returns the first time after t matching schedule,
+// zero time is returned if there is no such time.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	limit := t.AddDate(maxYears, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
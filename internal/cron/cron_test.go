@@ -0,0 +1,52 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2021, time.August, 31, 23, 50, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2021, time.August, 31, 23, 51, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, time.September, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 1-5", time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC)},
+		{"30 9 1,15 * *", time.Date(2021, time.September, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * 12 0", time.Date(2021, time.December, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 18 13 * 5", time.Date(2021, time.September, 3, 18, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+		{"0 0 */2 * 1", time.Date(2021, time.September, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * */2", time.Date(2021, time.November, 13, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		s, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+
+		if actual := s.Next(from); !actual.Equal(tc.expected) {
+			t.Errorf("%q:\nexpected: '%v',\nactual: '%v'", tc.expr, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package ffz
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/ihrk/microbot/internal/backoff"
 )
 
 const (
@@ -21,11 +24,26 @@ func (e Emote) ImageURL() string {
 	return fmt.Sprintf(emoteURL, e.ID)
 }
 
-func GetGlobalEmotes() ([]Emote, error) {
-	resp, err := http.Get(globalEmotesURL)
+func get(ctx context.Context, url string) (*http.Response, error) {
+	resp, err := backoff.Get(ctx, backoff.HTTP, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		resp.Body.Close()
+		return nil, fmt.Errorf("request failed with status: %s", resp.Status)
+	}
+
+	return resp, nil
+}
+
+func GetGlobalEmotes(ctx context.Context) ([]Emote, error) {
+	resp, err := get(ctx, globalEmotesURL)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	var emotes []Emote
 
@@ -37,13 +55,14 @@ func GetGlobalEmotes() ([]Emote, error) {
 	return emotes, nil
 }
 
-func GetUserEmotes(userID string) ([]Emote, error) {
+func GetUserEmotes(ctx context.Context, userID string) ([]Emote, error) {
 	url := fmt.Sprintf(userEmotesURL, userID)
 
-	resp, err := http.Get(url)
+	resp, err := get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	var emotes []Emote
 
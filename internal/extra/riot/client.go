@@ -1,15 +1,22 @@
 package riot
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
+
+	"github.com/ihrk/microbot/internal/backoff"
 )
 
 const riotApiDomain = ".api.riotgames.com"
 
+// requestTimeout limits request including retries.
+const requestTimeout = 10 * time.Second
+
 var regions = map[string]string{
 	"euw":  "euw1",
 	"ru":   "ru",
@@ -43,7 +50,7 @@ func NewClient(region, apiKey string) (*Client, error) {
 	}, nil
 }
 
-func (c *Client) doRequest(path string, v interface{}) error {
+func (c *Client) doRequest(ctx context.Context, path string, v interface{}) error {
 	var u url.URL
 
 	u.Scheme = "https"
@@ -54,7 +61,10 @@ func (c *Client) doRequest(path string, v interface{}) error {
 	q.Set("api_key", c.apiKey)
 	u.RawQuery = q.Encode()
 
-	resp, err := http.Get(u.String())
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	resp, err := backoff.Get(ctx, backoff.HTTP, u.String())
 	if err != nil {
 		return err
 	}
@@ -1,18 +1,21 @@
 package riot
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type Summoner struct {
 	ID        string `json:"id"`
 	AccountID string `json:"accountID"`
 }
 
-func (c *Client) GetSummonerByName(name string) (*Summoner, error) {
+func (c *Client) GetSummonerByName(ctx context.Context, name string) (*Summoner, error) {
 	var s Summoner
 
 	path := fmt.Sprintf("/lol/summoner/v4/summoners/by-name/%s", name)
 
-	err := c.doRequest(path, &s)
+	err := c.doRequest(ctx, path, &s)
 	if err != nil {
 		return nil, err
 	}
@@ -41,12 +44,12 @@ func (e *LeagueEntry) String() string {
 	)
 }
 
-func (c *Client) GetLeagueEntriesBySummoner(summonerID string) ([]LeagueEntry, error) {
+func (c *Client) GetLeagueEntriesBySummoner(ctx context.Context, summonerID string) ([]LeagueEntry, error) {
 	var entries []LeagueEntry
 
 	path := fmt.Sprintf("/lol/league/v4/entries/by-summoner/%s", summonerID)
 
-	err := c.doRequest(path, &entries)
+	err := c.doRequest(ctx, path, &entries)
 	if err != nil {
 		return nil, err
 	}
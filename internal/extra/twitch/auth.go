@@ -0,0 +1,164 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultAuthURL = "https://id.twitch.tv/oauth2"
+
+// ErrInvalidToken is returned if token is expired or revoked.
+var ErrInvalidToken = errors.New("invalid token")
+
+// AuthClient calls Twitch OAuth endpoints of the application
+// identified by ClientID and ClientSecret.
+type AuthClient struct {
+	ClientID     string
+	ClientSecret string
+
+	// URL is base URL of OAuth endpoints, default is used if empty.
+	URL  string
+	HTTP *http.Client
+}
+
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// Validation is information about valid token.
+type Validation struct {
+	Login     string
+	UserID    string
+	ExpiresIn time.Duration
+}
+
+type validateResponse struct {
+	Login     string `json:"login"`
+	UserID    string `json:"user_id"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type APIError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("twitch auth call ended with status: %d, msg: '%s'", e.Status, e.Message)
+}
+
+func (c *AuthClient) url(path string) string {
+	base := c.URL
+	if base == "" {
+		base = defaultAuthURL
+	}
+
+	return strings.TrimSuffix(base, "/") + path
+}
+
+func (c *AuthClient) do(req *http.Request, v interface{}) error {
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		e := APIError{Status: resp.StatusCode}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("%w: %v", ErrInvalidToken, &e)
+		}
+
+		return &e
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Validate checks access token, Twitch requires applications
+// to validate tokens on start and hourly.
+func (c *AuthClient) Validate(ctx context.Context, accessToken string) (*Validation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/validate"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "OAuth "+accessToken)
+
+	var resp validateResponse
+
+	err = c.do(req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Validation{
+		Login:     resp.Login,
+		UserID:    resp.UserID,
+		ExpiresIn: time.Duration(resp.ExpiresIn) * time.Second,
+	}, nil
+}
+
+// Refresh gets new access token, refresh token may be rotated too.
+func (c *AuthClient) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/token"),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	now := time.Now()
+
+	var resp tokenResponse
+
+	err = c.do(req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	tok := &Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+	}
+
+	// zero expires_in is returned for tokens that do not expire
+	if resp.ExpiresIn > 0 {
+		tok.Expiry = now.Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+
+	if tok.RefreshToken == "" {
+		tok.RefreshToken = refreshToken
+	}
+
+	return tok, nil
+}
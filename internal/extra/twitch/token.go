@@ -0,0 +1,218 @@
+package twitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// refreshMargin is time before expiry when token is refreshed.
+	refreshMargin = 5 * time.Minute
+	// validatePeriod is required by Twitch for long running apps.
+	validatePeriod = time.Hour
+	retryPeriod    = time.Minute
+	// minCheckPeriod keeps Run from checking token in a loop
+	// if Twitch reports expiry which is already close.
+	minCheckPeriod = 10 * time.Second
+)
+
+// TokenStore keeps token between restarts, Load returns nil
+// if there is no token.
+type TokenStore interface {
+	Load() (*Token, error)
+	Save(tok *Token) error
+}
+
+// TokenSource keeps user access token valid: it is validated
+// periodically and refreshed before expiry. Rotated tokens
+// are saved to store.
+type TokenSource struct {
+	c     *AuthClient
+	store TokenStore
+
+	m     sync.Mutex
+	tok   Token
+	login string
+}
+
+// NewTokenSource creates source which starts from refreshToken
+// if store has no token.
+func NewTokenSource(c *AuthClient, store TokenStore, refreshToken string) *TokenSource {
+	return &TokenSource{
+		c:     c,
+		store: store,
+		tok:   Token{RefreshToken: refreshToken},
+	}
+}
+
+// Start loads token from store and validates it, token
+// is refreshed if it is missing or invalid.
+func (s *TokenSource) Start(ctx context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	stored, err := s.store.Load()
+	if err != nil {
+		return err
+	}
+
+	if stored != nil {
+		s.tok = *stored
+	}
+
+	if s.tok.AccessToken != "" {
+		err = s.validate(ctx)
+		if !errors.Is(err, ErrInvalidToken) {
+			return err
+		}
+
+		log.Println("stored twitch token is invalid, refreshing")
+	}
+
+	err = s.refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.validate(ctx)
+}
+
+// validate updates expiry and login of current token, zero
+// expires_in means that token does not expire.
+func (s *TokenSource) validate(ctx context.Context) error {
+	v, err := s.c.Validate(ctx, s.tok.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	s.tok.Expiry = time.Time{}
+	if v.ExpiresIn > 0 {
+		s.tok.Expiry = time.Now().Add(v.ExpiresIn)
+	}
+
+	s.login = v.Login
+
+	return nil
+}
+
+// expiresSoon reports whether token must be refreshed,
+// token without expiry is refreshed only if it is rejected.
+func (s *TokenSource) expiresSoon() bool {
+	return !s.tok.Expiry.IsZero() && time.Until(s.tok.Expiry) < refreshMargin
+}
+
+func (s *TokenSource) refresh(ctx context.Context) error {
+	tok, err := s.c.Refresh(ctx, s.tok.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("twitch token refresh failed: %w", err)
+	}
+
+	s.tok = *tok
+
+	if err = s.store.Save(tok); err != nil {
+		log.Printf("twitch token save error: %v\n", err)
+	}
+
+	return nil
+}
+
+// Token returns access token, it is refreshed if it expires soon.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.expiresSoon() {
+		if err := s.refresh(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	return s.tok.AccessToken, nil
+}
+
+// Refresh replaces access token even if it is not expired,
+// it is used when token is rejected.
+func (s *TokenSource) Refresh(ctx context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.refresh(ctx)
+}
+
+// Login returns login of token owner.
+func (s *TokenSource) Login() string {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.login
+}
+
+// Run validates token hourly and refreshes it before expiry
+// until ctx is done.
+func (s *TokenSource) Run(ctx context.Context) {
+	wait := s.next()
+
+	for {
+		t := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		if err := s.check(ctx); err != nil {
+			log.Printf("twitch token check error: %v\n", err)
+
+			wait = retryPeriod
+			continue
+		}
+
+		wait = s.next()
+	}
+}
+
+func (s *TokenSource) check(ctx context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if !s.expiresSoon() {
+		err := s.validate(ctx)
+		if !errors.Is(err, ErrInvalidToken) {
+			return err
+		}
+	}
+
+	err := s.refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.validate(ctx)
+}
+
+// next returns time until next check.
+func (s *TokenSource) next() time.Duration {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.tok.Expiry.IsZero() {
+		return validatePeriod
+	}
+
+	wait := time.Until(s.tok.Expiry) - refreshMargin
+	if wait > validatePeriod {
+		wait = validatePeriod
+	}
+
+	if wait < minCheckPeriod {
+		wait = minCheckPeriod
+	}
+
+	return wait
+}
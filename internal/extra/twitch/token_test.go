@@ -0,0 +1,192 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// authServer is a stand-in for Twitch OAuth endpoints which
+// rotates both tokens on every refresh.
+type authServer struct {
+	m         sync.Mutex
+	access    string
+	refresh   string
+	expiresIn int64
+	refreshes int
+}
+
+func (srv *authServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.m.Lock()
+	defer srv.m.Unlock()
+
+	switch r.URL.Path {
+	case "/validate":
+		if r.Header.Get("Authorization") != "OAuth "+srv.access {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"status":401,"message":"invalid access token"}`))
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(validateResponse{
+			Login:     "microbot",
+			UserID:    "42",
+			ExpiresIn: srv.expiresIn,
+		})
+	case "/token":
+		_ = r.ParseForm()
+
+		if r.PostForm.Get("client_id") != "id" ||
+			r.PostForm.Get("client_secret") != "secret" ||
+			r.PostForm.Get("refresh_token") != srv.refresh {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":400,"message":"Invalid refresh token"}`))
+			return
+		}
+
+		srv.refreshes++
+		srv.access = strings.Repeat("a", srv.refreshes)
+		srv.refresh = strings.Repeat("r", srv.refreshes)
+
+		_ = json.NewEncoder(w).Encode(tokenResponse{
+			AccessToken:  srv.access,
+			RefreshToken: srv.refresh,
+			ExpiresIn:    srv.expiresIn,
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type memoryTokenStore struct {
+	tok *Token
+}
+
+func (s *memoryTokenStore) Load() (*Token, error) {
+	return s.tok, nil
+}
+
+func (s *memoryTokenStore) Save(tok *Token) error {
+	s.tok = tok
+	return nil
+}
+
+func TestTokenSource(t *testing.T) {
+	as := &authServer{refresh: "initial", expiresIn: 3600}
+
+	ts := httptest.NewServer(as)
+	defer ts.Close()
+
+	c := &AuthClient{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		URL:          ts.URL,
+		HTTP:         ts.Client(),
+	}
+
+	store := &memoryTokenStore{tok: &Token{AccessToken: "revoked", RefreshToken: "initial"}}
+	src := NewTokenSource(c, store, "initial")
+
+	ctx := context.Background()
+
+	// stored token is invalid, so it is refreshed
+	if err := src.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := src.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tok != "a" || src.Login() != "microbot" {
+		t.Errorf("unexpected token: %s, login: %s", tok, src.Login())
+	}
+
+	if store.tok.RefreshToken != "r" {
+		t.Errorf("rotated refresh token is not saved: %s", store.tok.RefreshToken)
+	}
+
+	// token that expires soon is refreshed before use
+	as.m.Lock()
+	as.expiresIn = 60
+	as.m.Unlock()
+
+	if err = src.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err = src.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tok != "aaa" || as.refreshes != 3 {
+		t.Errorf("unexpected token: %s, refreshes: %d", tok, as.refreshes)
+	}
+
+	// restart with saved token does not refresh it
+	as.m.Lock()
+	as.expiresIn = 3600
+	as.m.Unlock()
+
+	src = NewTokenSource(c, store, "initial")
+	if err = src.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if as.refreshes != 3 {
+		t.Errorf("valid token is refreshed, refreshes: %d", as.refreshes)
+	}
+}
+
+func TestTokenSourceWithoutExpiry(t *testing.T) {
+	as := &authServer{refresh: "initial"}
+
+	ts := httptest.NewServer(as)
+	defer ts.Close()
+
+	c := &AuthClient{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		URL:          ts.URL,
+		HTTP:         ts.Client(),
+	}
+
+	src := NewTokenSource(c, &memoryTokenStore{}, "initial")
+
+	ctx := context.Background()
+
+	if err := src.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// zero expires_in must not make every use refresh token
+	for i := 0; i < 3; i++ {
+		if _, err := src.Token(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if as.refreshes != 1 {
+		t.Errorf("token without expiry is refreshed, refreshes: %d", as.refreshes)
+	}
+
+	if wait := src.next(); wait != validatePeriod {
+		t.Errorf("unexpected wait before check: %v", wait)
+	}
+
+	// token that is about to expire is not checked in a loop
+	src.m.Lock()
+	src.tok.Expiry = time.Now().Add(time.Second)
+	src.m.Unlock()
+
+	if wait := src.next(); wait < minCheckPeriod {
+		t.Errorf("unexpected wait before check: %v", wait)
+	}
+}
@@ -3,39 +3,92 @@ package irc
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"nhooyr.io/websocket"
 )
 
 const (
-	chatURL = "wss://irc-ws.chat.twitch.tv:443"
+	ChatURL = "wss://irc-ws.chat.twitch.tv:443"
 
-	CapMembership = ":twitch.tv/membership"
-	CapTags       = ":twitch.tv/tags"
-	CapCommands   = ":twitch.tv/commands"
+	CapMembership = "twitch.tv/membership"
+	CapTags       = "twitch.tv/tags"
+	CapCommands   = "twitch.tv/commands"
 )
 
+var (
+	ErrReconnect   = errors.New("server requested reconnect")
+	ErrLoginFailed = errors.New("login failed")
+)
+
+var loginFailures = []string{
+	"Login authentication failed",
+	"Login unsuccessful",
+	"Improperly formatted auth",
+}
+
+// Recorder receives every raw line read by Client.
+type Recorder interface {
+	Record(line string)
+}
+
 type Client struct {
-	ctx  context.Context
-	conn *websocket.Conn
-	rd   *bufio.Reader
+	ctx     context.Context // cancelled on disconnect
+	cancel  context.CancelFunc
+	conn    *websocket.Conn
+	rd      *bufio.Reader
+	q       *sendQueue
+	done    chan struct{}
+	stopped chan struct{} // closed when writeLoop returns
+	once    sync.Once
+	rec     Recorder // nil if lines are not recorded
+
+	// welcomed is closed when server accepts login, queued
+	// messages are not sent before it
+	welcomed    chan struct{}
+	welcomeOnce sync.Once
 }
 
 func Dial(ctx context.Context, timeout time.Duration) (*Client, error) {
+	return DialURL(ctx, ChatURL, timeout)
+}
+
+// DialURL connects to chat server at url, it is used
+// to connect to servers other than Twitch in tests.
+func DialURL(ctx context.Context, url string, timeout time.Duration) (*Client, error) {
 	opts := &websocket.DialOptions{
 		HTTPClient: &http.Client{Timeout: timeout},
 	}
 
-	conn, _, err := websocket.Dial(ctx, chatURL, opts)
+	conn, _, err := websocket.Dial(ctx, url, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{ctx, conn, bufio.NewReader(nil)}, nil
+	// connection outlives ctx of dial, so it can be
+	// closed gracefully after ctx is cancelled
+	connCtx, cancel := context.WithCancel(context.Background())
+
+	c := &Client{
+		ctx:     connCtx,
+		cancel:  cancel,
+		conn:    conn,
+		rd:      bufio.NewReader(nil),
+		q:       newSendQueue(),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+
+		welcomed: make(chan struct{}),
+	}
+
+	go c.writeLoop()
+
+	return c, nil
 }
 
 const linebreak = "\r\n"
@@ -49,21 +102,35 @@ func fmtChannel(channel string) string {
 	return strings.ToLower(channel)
 }
 
-func (c *Client) pong() error {
-	return c.printf("PONG :tmi.twitch.tv")
+func (c *Client) pong(ping *Msg) error {
+	return c.WriteMsg(&Msg{
+		Type: MsgTypePong,
+		Text: ping.Text,
+	})
 }
 
 func (c *Client) Login(nick, pass string) error {
-	if err := c.printf("PASS %s", pass); err != nil {
+	err := c.WriteMsg(&Msg{
+		Type:   MsgTypePass,
+		Params: []string{pass},
+	})
+	if err != nil {
 		return err
 	}
 
-	return c.printf("NICK %s", nick)
+	return c.WriteMsg(&Msg{
+		Type:   MsgTypeNick,
+		Params: []string{nick},
+	})
 }
 
 func (c *Client) RegCaps(caps ...string) error {
 	for _, cap := range caps {
-		err := c.printf("CAP REQ %s", cap)
+		err := c.WriteMsg(&Msg{
+			Type:   MsgTypeCap,
+			Params: []string{"REQ"},
+			Text:   cap,
+		})
 		if err != nil {
 			return err
 		}
@@ -72,23 +139,125 @@ func (c *Client) RegCaps(caps ...string) error {
 }
 
 func (c *Client) Join(channel string) error {
-	return c.printf("JOIN #%s", fmtChannel(channel))
+	return c.WriteMsg(&Msg{
+		Type:   MsgTypeJoin,
+		Params: []string{"#" + fmtChannel(channel)},
+	})
 }
 
+func (c *Client) Part(channel string) error {
+	return c.WriteMsg(&Msg{
+		Type:   MsgTypeLeave,
+		Params: []string{"#" + fmtChannel(channel)},
+	})
+}
+
+// Quit tells server that client is leaving.
+func (c *Client) Quit() error {
+	return c.WriteMsg(&Msg{Type: MsgTypeQuit})
+}
+
+// PrivMsg puts message into send queue, moderation commands
+// are sent ahead of chat messages. Queue is not sent until
+// server welcomes client after login.
 func (c *Client) PrivMsg(channel, msg string) error {
-	return c.printf("PRIVMSG #%s :%s", fmtChannel(channel), fmtMsg(msg))
+	return c.q.push(NewPrivMsg(channel, msg))
 }
 
 func (c *Client) PrivMsgReply(channel, msg, parentMsgID string) error {
-	return c.printf("@reply-parent-msg-id=%s PRIVMSG #%s :%s", parentMsgID, fmtChannel(channel), fmtMsg(msg))
+	m := NewPrivMsg(channel, msg)
+	m.Tags = map[string]string{
+		"reply-parent-msg-id": parentMsgID,
+	}
+
+	return c.q.push(m)
+}
+
+// Adopt moves messages that prev has not sent yet to the front of c's queue,
+// rate limits of prev are kept. prev must be disconnected.
+func (c *Client) Adopt(prev *Client) {
+	c.q.adopt(prev.q)
 }
+
+// SetRecorder makes c pass every line it reads to rec,
+// it has to be called before reading.
+func (c *Client) SetRecorder(rec Recorder) {
+	c.rec = rec
+}
+
+// Stats returns current state of send queue.
+func (c *Client) Stats() QueueStats {
+	return c.q.stats()
+}
+
+// Flush waits until all queued messages are sent.
+func (c *Client) Flush(ctx context.Context) error {
+	select {
+	case <-c.q.empty():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Disconnect closes connection and waits until c stops
+// sending messages of its queue.
 func (c *Client) Disconnect() error {
-	return c.conn.Close(websocket.StatusNormalClosure, "client disconnect")
+	c.once.Do(func() { close(c.done) })
+	err := c.conn.Close(websocket.StatusNormalClosure, "client disconnect")
+	c.cancel()
+
+	<-c.stopped
+
+	return err
 }
 
-func (c *Client) printf(format string, args ...interface{}) error {
+func (c *Client) writeLoop() {
+	defer close(c.stopped)
+
+	// server drops messages sent before login and join,
+	// replies adopted from previous connection included
+	select {
+	case <-c.welcomed:
+	case <-c.done:
+		return
+	}
+
+	for {
+		msg, lane, ok := c.q.peek()
+		if !ok {
+			select {
+			case <-c.q.ready:
+				continue
+			case <-c.done:
+				return
+			}
+		}
+
+		if d := c.q.delay(msg.Channel); d > 0 {
+			t := time.NewTimer(d)
+
+			select {
+			case <-t.C:
+				continue
+			case <-c.done:
+				t.Stop()
+				return
+			}
+		}
+
+		if err := c.WriteMsg(msg); err != nil {
+			return
+		}
+
+		c.q.commit(lane)
+	}
+}
+
+// WriteMsg encodes and sends msg immediately, bypassing send queue.
+func (c *Client) WriteMsg(msg *Msg) error {
 	return c.conn.Write(c.ctx, websocket.MessageText,
-		[]byte(fmt.Sprintf(format, args...)))
+		[]byte(msg.Encode()))
 }
 
 func (c *Client) ReadMsg(ctx context.Context) (*Msg, error) {
@@ -107,17 +276,48 @@ func (c *Client) ReadMsg(ctx context.Context) (*Msg, error) {
 			return nil, err
 		}
 
-		if strings.HasPrefix(line, "PING") {
-			err = c.pong()
+		line = strings.TrimSuffix(line, linebreak)
+
+		if c.rec != nil {
+			c.rec.Record(line)
+		}
+
+		msg := ParseMsg(line)
+
+		switch msg.Type {
+		case MsgTypePing:
+			err = c.pong(msg)
 			if err != nil {
 				return nil, err
 			}
 
 			continue
+		case MsgTypeWelcome:
+			c.welcomeOnce.Do(func() { close(c.welcomed) })
+		case MsgTypeUserState:
+			c.q.setMod(msg.Channel, msg.IsMod() || msg.IsBroadcaster())
+		case MsgTypeReconnect:
+			return nil, ErrReconnect
+		case MsgTypeNotice:
+			if isLoginFailure(msg) {
+				return nil, fmt.Errorf("%w: %s", ErrLoginFailed, msg.Text)
+			}
 		}
 
-		line = strings.TrimSuffix(line, linebreak)
+		return msg, nil
+	}
+}
 
-		return ParseMsg(line), nil
+func isLoginFailure(msg *Msg) bool {
+	if msg.Channel != "" {
+		return false
 	}
+
+	for _, text := range loginFailures {
+		if msg.Text == text {
+			return true
+		}
+	}
+
+	return false
 }
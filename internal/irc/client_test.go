@@ -0,0 +1,89 @@
+package irc_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ihrk/microbot/internal/irc"
+	"github.com/ihrk/microbot/internal/irc/irctest"
+)
+
+const testTimeout = 5 * time.Second
+
+func TestAdoptSendsAfterLogin(t *testing.T) {
+	fake := irctest.NewServer()
+	defer fake.Close()
+
+	ctx := context.Background()
+
+	prev, err := irc.DialURL(ctx, fake.URL, testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reply is queued, but server asks to reconnect before login
+	if err = prev.PrivMsg("first", "queued reply"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = fake.Send(":tmi.twitch.tv RECONNECT"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = prev.ReadMsg(ctx); !errors.Is(err, irc.ErrReconnect) {
+		t.Fatalf("expected reconnect, got: %v", err)
+	}
+
+	prev.Disconnect()
+
+	c, err := irc.DialURL(ctx, fake.URL, testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	c.Adopt(prev)
+
+	if err = c.RegCaps(irc.CapTags, irc.CapCommands); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Login("bot", "oauth:token"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Join("first"); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			if _, err := c.ReadMsg(ctx); err != nil {
+				return
+			}
+		}
+	}()
+
+	var types []string
+
+	_, err = fake.Expect(testTimeout, func(msg *irc.Msg) bool {
+		types = append(types, msg.Type)
+		return msg.Type == irc.MsgTypePrivMsg
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		irc.MsgTypeCap, irc.MsgTypeCap,
+		irc.MsgTypePass, irc.MsgTypeNick,
+		irc.MsgTypeJoin, irc.MsgTypePrivMsg,
+	}
+
+	if !reflect.DeepEqual(expected, types) {
+		t.Errorf("\nexpected: '%v',\nactual: '%v'", expected, types)
+	}
+}
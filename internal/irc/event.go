@@ -0,0 +1,77 @@
+package irc
+
+// Event types of USERNOTICE messages, passed in msg-id tag.
+const (
+	EventSub              = "sub"
+	EventResub            = "resub"
+	EventSubGift          = "subgift"
+	EventSubMysteryGift   = "submysterygift"
+	EventGiftPaidUpgrade  = "giftpaidupgrade"
+	EventPrimePaidUpgrade = "primepaidupgrade"
+	EventRaid             = "raid"
+	EventAnnouncement     = "announcement"
+	EventBitsBadgeTier    = "bitsbadgetier"
+	EventRitual           = "ritual"
+)
+
+var subPlans = map[string]string{
+	"Prime": "Prime",
+	"1000":  "1",
+	"2000":  "2",
+	"3000":  "3",
+}
+
+// Event is a typed view of USERNOTICE message. User is the one who
+// triggered event: subscriber, gifter or raider.
+type Event struct {
+	Type        string
+	User        string
+	DisplayName string
+
+	Months       int
+	StreakMonths int
+	Tier         string
+
+	Recipient            string
+	RecipientDisplayName string
+	GiftCount            int
+
+	ViewerCount int
+	Threshold   int
+
+	SystemMsg string
+	Text      string
+}
+
+func (msg *Msg) Event() (*Event, bool) {
+	if msg.Type != MsgTypeUserNotice {
+		return nil, false
+	}
+
+	e := Event{
+		Type:                 msg.Tags["msg-id"],
+		User:                 msg.Tags["login"],
+		DisplayName:          msg.Tags["display-name"],
+		Months:               msg.intTag("msg-param-cumulative-months"),
+		StreakMonths:         msg.intTag("msg-param-streak-months"),
+		Tier:                 subPlans[msg.Tags["msg-param-sub-plan"]],
+		Recipient:            msg.Tags["msg-param-recipient-user-name"],
+		RecipientDisplayName: msg.Tags["msg-param-recipient-display-name"],
+		GiftCount:            msg.intTag("msg-param-mass-gift-count"),
+		ViewerCount:          msg.intTag("msg-param-viewerCount"),
+		Threshold:            msg.intTag("msg-param-threshold"),
+		SystemMsg:            msg.Tags["system-msg"],
+		Text:                 msg.Text,
+	}
+
+	if e.Months == 0 {
+		// gifted subs carry months in a separate tag
+		e.Months = msg.intTag("msg-param-months")
+	}
+
+	if e.DisplayName == "" {
+		e.DisplayName = e.User
+	}
+
+	return &e, e.Type != ""
+}
@@ -0,0 +1,204 @@
+// Package irctest provides fake Twitch chat server for tests.
+package irctest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ihrk/microbot/internal/irc"
+	"nhooyr.io/websocket"
+)
+
+const host = "tmi.twitch.tv"
+
+var ErrNotConnected = errors.New("client is not connected")
+
+// Server accepts websocket connections and answers CAP, PASS, NICK,
+// JOIN, PART and PING like Twitch does. Everything sent by clients
+// is recorded and can be awaited with Expect.
+type Server struct {
+	URL string
+
+	hs       *httptest.Server
+	received chan *irc.Msg
+
+	m    sync.Mutex
+	conn *websocket.Conn // last accepted connection
+	nick string
+}
+
+const receivedBuf = 1024
+
+func NewServer() *Server {
+	s := &Server{
+		received: make(chan *irc.Msg, receivedBuf),
+	}
+
+	s.hs = httptest.NewServer(http.HandlerFunc(s.accept))
+	s.URL = "ws" + strings.TrimPrefix(s.hs.URL, "http")
+
+	return s
+}
+
+func (s *Server) Close() {
+	s.m.Lock()
+	if s.conn != nil {
+		s.conn.Close(websocket.StatusGoingAway, "server closed")
+	}
+	s.m.Unlock()
+
+	s.hs.Close()
+}
+
+func (s *Server) accept(w http.ResponseWriter, r *http.Request) {
+	// Twitch does not compress messages
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		CompressionMode: websocket.CompressionDisabled,
+	})
+	if err != nil {
+		return
+	}
+
+	s.m.Lock()
+	s.conn = conn
+	s.m.Unlock()
+
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	ctx := r.Context()
+
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+
+		for _, line := range strings.Split(string(data), "\r\n") {
+			if line == "" {
+				continue
+			}
+
+			msg := irc.ParseMsg(line)
+
+			if err = s.answer(ctx, conn, msg); err != nil {
+				return
+			}
+
+			s.received <- msg
+		}
+	}
+}
+
+func (s *Server) answer(ctx context.Context, conn *websocket.Conn, msg *irc.Msg) error {
+	s.m.Lock()
+	nick := s.nick
+	s.m.Unlock()
+
+	switch msg.Type {
+	case irc.MsgTypeCap:
+		return write(ctx, conn, ":"+host+" CAP * ACK :"+msg.Text)
+	case irc.MsgTypeNick:
+		nick = msg.Params[0]
+
+		s.m.Lock()
+		s.nick = nick
+		s.m.Unlock()
+
+		return write(ctx, conn,
+			":"+host+" 001 "+nick+" :Welcome, GLHF!",
+			":"+host+" 376 "+nick+" :>",
+			"@display-name="+nick+" :"+host+" GLOBALUSERSTATE",
+		)
+	case irc.MsgTypeJoin:
+		channel := msg.Params[0]
+
+		return write(ctx, conn,
+			":"+nick+"!"+nick+"@"+nick+"."+host+" JOIN "+channel,
+			"@badges=;display-name="+nick+";mod=0 :"+host+" USERSTATE "+channel,
+		)
+	case irc.MsgTypeLeave:
+		return write(ctx, conn,
+			":"+nick+"!"+nick+"@"+nick+"."+host+" PART "+msg.Params[0])
+	case irc.MsgTypePing:
+		return write(ctx, conn, ":"+host+" PONG "+host+" :"+msg.Text)
+	}
+
+	return nil
+}
+
+func write(ctx context.Context, conn *websocket.Conn, lines ...string) error {
+	data := strings.Join(lines, "\r\n") + "\r\n"
+	return conn.Write(ctx, websocket.MessageText, []byte(data))
+}
+
+// Send writes raw lines to the last connected client,
+// they may have tags and prefix.
+func (s *Server) Send(lines ...string) error {
+	s.m.Lock()
+	conn := s.conn
+	s.m.Unlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	return write(ctx, conn, lines...)
+}
+
+// PrivMsg sends chat message of user to channel, tags
+// such as badges, mod or id are added as given.
+func (s *Server) PrivMsg(channel, user, text string, tags map[string]string) error {
+	msg := &irc.Msg{
+		Tags: tags,
+		Prefix: irc.Prefix{
+			Nick: user,
+			User: user,
+			Host: user + "." + host,
+		},
+		Type:   irc.MsgTypePrivMsg,
+		Params: []string{"#" + channel},
+		Text:   text,
+	}
+
+	return s.Send(msg.Encode())
+}
+
+// Ping checks that client answers with PONG.
+func (s *Server) Ping(timeout time.Duration) error {
+	err := s.Send("PING :" + host)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Expect(timeout, func(msg *irc.Msg) bool {
+		return msg.Type == irc.MsgTypePong
+	})
+
+	return err
+}
+
+// Expect waits for message sent by client that satisfies match,
+// messages received before it are discarded.
+func (s *Server) Expect(timeout time.Duration, match func(*irc.Msg) bool) (*irc.Msg, error) {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	for {
+		select {
+		case msg := <-s.received:
+			if match(msg) {
+				return msg, nil
+			}
+		case <-t.C:
+			return nil, context.DeadlineExceeded
+		}
+	}
+}
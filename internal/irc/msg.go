@@ -1,6 +1,7 @@
 package irc
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -19,11 +20,16 @@ const (
 	MsgTypeUserNotice      = "USERNOTICE"
 	MsgTypeUserState       = "USERSTATE"
 	MsgTypeGlobalUserState = "GLOBALUSERSTATE"
-
-	hostName    = "tmi.twitch.tv"
-	hostNameLen = len(hostName)
+	MsgTypePing            = "PING"
+	MsgTypePong            = "PONG"
+	MsgTypeCap             = "CAP"
+	MsgTypePass            = "PASS"
+	MsgTypeNick            = "NICK"
+	MsgTypeQuit            = "QUIT"
+	MsgTypeWelcome         = "001"
 )
 
+// ParseMsg parses raw IRC line without trailing CRLF.
 func ParseMsg(rawMsg string) *Msg {
 	var msg Msg
 
@@ -31,128 +37,284 @@ func ParseMsg(rawMsg string) *Msg {
 
 	msg.Tags, rawMsg = parseTags(rawMsg)
 
-	msg.User, rawMsg = parseUser(rawMsg)
+	msg.Prefix, rawMsg = parsePrefix(rawMsg)
+
+	msg.Type, rawMsg = nextToken(rawMsg)
 
-	msg.Type, rawMsg = parseType(rawMsg)
+	msg.Params, msg.Text = parseParams(rawMsg)
 
-	msg.Channel, rawMsg = parseChannel(rawMsg)
+	msg.User = msg.Prefix.Nick
 
-	msg.Text = parseText(rawMsg)
+	msg.Channel = parseChannel(msg.Params)
 
 	return &msg
 }
 
+func nextToken(rawMsg string) (string, string) {
+	rawMsg = strings.TrimLeft(rawMsg, " ")
+
+	endOff := strings.IndexByte(rawMsg, ' ')
+	if endOff == -1 {
+		return rawMsg, ""
+	}
+
+	return rawMsg[:endOff], rawMsg[endOff+1:]
+}
+
 func parseTags(rawMsg string) (map[string]string, string) {
 	if strings.IndexByte(rawMsg, '@') != 0 {
 		return nil, rawMsg
 	}
 
-	var rawTags, tail string
-	if endOff := strings.IndexByte(rawMsg, ' '); endOff != -1 {
-		rawTags = rawMsg[1:endOff]
-		tail = rawMsg[endOff+1:]
-	} else {
-		rawTags = rawMsg[1:]
-	}
+	rawTags, tail := nextToken(rawMsg[1:])
 
 	tags := map[string]string{}
 
 	pairs := strings.Split(rawTags, ";")
 
 	for _, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+
 		sepOff := strings.IndexByte(pair, '=')
 		if sepOff == -1 {
+			tags[pair] = ""
 			continue
 		}
 
-		tagName := string(pair[:sepOff])
-		tagValue := string(pair[sepOff+1:])
-
-		tags[tagName] = strings.ReplaceAll(tagValue, "\\s", " ")
+		tags[pair[:sepOff]] = unescapeTag(pair[sepOff+1:])
 	}
 
 	return tags, tail
 }
 
-func parseUser(rawMsg string) (string, string) {
-	if strings.IndexByte(rawMsg, ':') != 0 {
-		return "", rawMsg
+func unescapeTag(value string) string {
+	if strings.IndexByte(value, '\\') == -1 {
+		return value
 	}
 
-	hostOff := strings.Index(rawMsg, hostName)
-	if hostOff == -1 {
-		return "", rawMsg
-	}
+	var sb strings.Builder
 
-	var tail string
-	if endOff := strings.IndexByte(rawMsg, ' '); endOff != -1 {
-		tail = rawMsg[endOff+1:]
-	}
+	sb.Grow(len(value))
 
-	if hostOff == 1 {
-		return "", tail
-	}
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			sb.WriteByte(value[i])
+			continue
+		}
 
-	atOff := strings.IndexByte(rawMsg, '@')
+		i++
+		if i == len(value) {
+			break
+		}
 
-	var user string
-	if atOff != -1 && atOff < hostOff-1 {
-		user = string(rawMsg[atOff+1 : hostOff-1])
+		switch value[i] {
+		case ':':
+			sb.WriteByte(';')
+		case 's':
+			sb.WriteByte(' ')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'n':
+			sb.WriteByte('\n')
+		default:
+			sb.WriteByte(value[i])
+		}
 	}
 
-	return user, tail
+	return sb.String()
 }
 
-func parseType(rawMsg string) (string, string) {
-	endOff := strings.IndexByte(rawMsg, ' ')
+func escapeTag(value string) string {
+	var sb strings.Builder
 
-	var tp, tail string
-	if endOff != -1 {
-		tp = rawMsg[:endOff]
-		tail = rawMsg[endOff+1:]
-	} else {
-		tp = rawMsg
+	sb.Grow(len(value))
+
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case ';':
+			sb.WriteString(`\:`)
+		case ' ':
+			sb.WriteString(`\s`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\n':
+			sb.WriteString(`\n`)
+		default:
+			sb.WriteByte(value[i])
+		}
 	}
 
-	return tp, tail
+	return sb.String()
 }
 
-func parseChannel(rawMsg string) (string, string) {
-	if strings.IndexByte(rawMsg, '#') != 0 {
-		return "", rawMsg
+func parsePrefix(rawMsg string) (Prefix, string) {
+	var p Prefix
+
+	rawMsg = strings.TrimLeft(rawMsg, " ")
+
+	if strings.IndexByte(rawMsg, ':') != 0 {
+		return p, rawMsg
 	}
 
-	endOff := strings.IndexByte(rawMsg, ' ')
+	rawPrefix, tail := nextToken(rawMsg[1:])
 
-	var channel, tail string
-	if endOff != -1 {
-		channel = rawMsg[1:endOff]
-		tail = rawMsg[endOff+1:]
-	} else {
-		channel = rawMsg[1:]
+	if atOff := strings.IndexByte(rawPrefix, '@'); atOff != -1 {
+		p.Host = rawPrefix[atOff+1:]
+		rawPrefix = rawPrefix[:atOff]
+	} else if strings.IndexByte(rawPrefix, '!') == -1 {
+		// prefix without user parts is a server name
+		p.Host = rawPrefix
+		return p, tail
+	}
+
+	if bangOff := strings.IndexByte(rawPrefix, '!'); bangOff != -1 {
+		p.User = rawPrefix[bangOff+1:]
+		rawPrefix = rawPrefix[:bangOff]
 	}
 
-	return channel, tail
+	p.Nick = rawPrefix
+
+	return p, tail
+}
+
+func parseParams(rawMsg string) ([]string, string) {
+	var params []string
+
+	for {
+		rawMsg = strings.TrimLeft(rawMsg, " ")
+
+		if rawMsg == "" {
+			return params, ""
+		}
+
+		if rawMsg[0] == ':' {
+			return params, rawMsg[1:]
+		}
+
+		var param string
+		param, rawMsg = nextToken(rawMsg)
+		params = append(params, param)
+	}
 }
 
-func parseText(rawMsg string) string {
-	off := strings.IndexByte(rawMsg, ':')
-	if off == -1 {
+func parseChannel(params []string) string {
+	if len(params) == 0 || strings.IndexByte(params[0], '#') != 0 {
 		return ""
 	}
 
-	return rawMsg[off+1:]
+	return params[0][1:]
+}
+
+// Prefix is the source of the message, for messages sent by server
+// only Host is set.
+type Prefix struct {
+	Nick string
+	User string
+	Host string
+}
+
+func (p Prefix) String() string {
+	if p.Nick == "" {
+		return p.Host
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(p.Nick)
+
+	if p.User != "" {
+		sb.WriteByte('!')
+		sb.WriteString(p.User)
+	}
+
+	if p.Host != "" {
+		sb.WriteByte('@')
+		sb.WriteString(p.Host)
+	}
+
+	return sb.String()
 }
 
+// Msg is a parsed IRC message. Params holds middle parameters
+// and Text holds trailing one. Channel and User are derived from
+// Params and Prefix on parsing and are not used for encoding.
 type Msg struct {
 	Tags    map[string]string
+	Prefix  Prefix
 	Type    string
+	Params  []string
 	Channel string
 	Text    string
 	User    string
 	Raw     string
 }
 
+func NewPrivMsg(channel, text string) *Msg {
+	channel = fmtChannel(channel)
+
+	return &Msg{
+		Type:    MsgTypePrivMsg,
+		Params:  []string{"#" + channel},
+		Channel: channel,
+		Text:    fmtMsg(text),
+	}
+}
+
+// Encode formats message as raw IRC line without trailing CRLF.
+func (msg *Msg) Encode() string {
+	var sb strings.Builder
+
+	if len(msg.Tags) > 0 {
+		keys := make([]string, 0, len(msg.Tags))
+		for k := range msg.Tags {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		sb.WriteByte('@')
+
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteByte(';')
+			}
+
+			sb.WriteString(k)
+
+			if v := msg.Tags[k]; v != "" {
+				sb.WriteByte('=')
+				sb.WriteString(escapeTag(v))
+			}
+		}
+
+		sb.WriteByte(' ')
+	}
+
+	if prefix := msg.Prefix.String(); prefix != "" {
+		sb.WriteByte(':')
+		sb.WriteString(prefix)
+		sb.WriteByte(' ')
+	}
+
+	sb.WriteString(msg.Type)
+
+	for _, param := range msg.Params {
+		sb.WriteByte(' ')
+		sb.WriteString(param)
+	}
+
+	if msg.Text != "" {
+		sb.WriteString(" :")
+		sb.WriteString(msg.Text)
+	}
+
+	return sb.String()
+}
+
 // dead code below
 
 const (
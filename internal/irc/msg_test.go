@@ -187,6 +187,172 @@ func TestParseMsg(t *testing.T) {
 	}
 }
 
+func TestParseTagEscapes(t *testing.T) {
+	tagCases := []struct {
+		raw      string
+		expected string
+	}{
+		{`a\sb`, "a b"},
+		{`a\:b`, "a;b"},
+		{`a\\b`, `a\b`},
+		{`a\rb\nc`, "a\rb\nc"},
+		{`a\bc`, "abc"},
+		{`abc\`, "abc"},
+		{`\\s`, `\s`},
+		{"", ""},
+	}
+
+	for _, tc := range tagCases {
+		msg := ParseMsg("@key=" + tc.raw + " :tmi.twitch.tv NOTICE #channel :text")
+		assertEq(t, tc.expected, msg.Tags["key"])
+	}
+
+	msg := ParseMsg("@flag;key=value :tmi.twitch.tv NOTICE #channel :text")
+	assertEq(t, map[string]string{"flag": "", "key": "value"}, msg.Tags)
+}
+
+func TestParsePrefixAndParams(t *testing.T) {
+	prefixCases := []struct {
+		rawMsg string
+		prefix Prefix
+		params []string
+	}{
+		{
+			rawMsg: "PING :tmi.twitch.tv",
+		},
+		{
+			rawMsg: ":tmi.twitch.tv CAP * ACK :twitch.tv/tags",
+			prefix: Prefix{Host: "tmi.twitch.tv"},
+			params: []string{"*", "ACK"},
+		},
+		{
+			rawMsg: ":viewer!viewer@viewer.tmi.twitch.tv JOIN #channel",
+			prefix: Prefix{Nick: "viewer", User: "viewer", Host: "viewer.tmi.twitch.tv"},
+			params: []string{"#channel"},
+		},
+		{
+			rawMsg: ":viewer.tmi.twitch.tv 353 viewer = #channel :viewer",
+			prefix: Prefix{Host: "viewer.tmi.twitch.tv"},
+			params: []string{"viewer", "=", "#channel"},
+		},
+		{
+			rawMsg: ":nick@host  PRIVMSG   #channel  :  spaced  text",
+			prefix: Prefix{Nick: "nick", Host: "host"},
+			params: []string{"#channel"},
+		},
+	}
+
+	for _, tc := range prefixCases {
+		msg := ParseMsg(tc.rawMsg)
+		assertEq(t, tc.prefix, msg.Prefix)
+		assertEq(t, tc.params, msg.Params)
+	}
+
+	msg := ParseMsg(":nick@host  PRIVMSG   #channel  :  spaced  text")
+	assertEq(t, "channel", msg.Channel)
+	assertEq(t, "  spaced  text", msg.Text)
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, tc := range cases {
+		expected := ParseMsg(tc.rawMsg)
+		actual := ParseMsg(expected.Encode())
+
+		assertEq(t, expected.Tags, actual.Tags)
+		assertEq(t, expected.Prefix, actual.Prefix)
+		assertEq(t, expected.Type, actual.Type)
+		assertEq(t, expected.Params, actual.Params)
+		assertEq(t, expected.Channel, actual.Channel)
+		assertEq(t, expected.Text, actual.Text)
+		assertEq(t, expected.User, actual.User)
+	}
+
+	raw := `@reply-parent-msg-body=a\sb\:c\\d;reply-parent-msg-id=id :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #channel :hi there`
+	assertEq(t, raw, ParseMsg(raw).Encode())
+}
+
+func TestEncode(t *testing.T) {
+	msg := NewPrivMsg("Channel", "  hello\r\nworld ")
+	msg.Tags = map[string]string{
+		"reply-parent-msg-id": "id; PRIVMSG #other :x",
+	}
+
+	assertEq(t,
+		`@reply-parent-msg-id=id\:\sPRIVMSG\s#other\s:x PRIVMSG #channel :hello world`,
+		msg.Encode())
+
+	msg = &Msg{Type: MsgTypePass, Params: []string{"oauth:token"}}
+	assertEq(t, "PASS oauth:token", msg.Encode())
+}
+
+func TestTagAccessors(t *testing.T) {
+	msg := ParseMsg(`@badge-info=founder/14;badges=founder/0,vip/1,premium/1;bits=100;color=#1E90FF;display-name=Viewer;emotes=25:6-10,14-18/1902:0-4;first-msg=1;id=msg-id;reply-parent-display-name=Other;reply-parent-msg-body=hi\sthere;reply-parent-msg-id=parent-id;reply-parent-user-id=42;reply-parent-user-login=other;returning-chatter=0;room-id=7;user-id=3 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #channel :Keepo Kappa 😀 Kappa`)
+
+	assertEq(t, Badges{{"founder", "0"}, {"vip", "1"}, {"premium", "1"}}, msg.Badges())
+	assertEq(t, 14, msg.SubMonths())
+	assertEq(t, false, msg.IsBroadcaster())
+	assertEq(t, false, msg.IsMod())
+	assertEq(t, true, msg.IsVIP())
+	assertEq(t, true, msg.IsSub())
+
+	emotes := msg.Emotes()
+	assertEq(t, []Emote{{"1902", 0, 4}, {"25", 6, 10}, {"25", 14, 18}}, emotes)
+	assertEq(t, "Keepo", emotes[0].Name(msg.Text))
+	assertEq(t, "Kappa", emotes[2].Name(msg.Text))
+
+	assertEq(t, "msg-id", msg.ID())
+	assertEq(t, "3", msg.UserID())
+	assertEq(t, "7", msg.RoomID())
+	assertEq(t, "Viewer", msg.DisplayName())
+	assertEq(t, "#1E90FF", msg.Color())
+	assertEq(t, true, msg.FirstMsg())
+	assertEq(t, false, msg.ReturningChatter())
+	assertEq(t, 100, msg.Bits())
+
+	parent, ok := msg.ReplyParent()
+	assertEq(t, true, ok)
+	assertEq(t, ReplyParent{
+		MsgID:       "parent-id",
+		UserID:      "42",
+		UserLogin:   "other",
+		DisplayName: "Other",
+		Body:        "hi there",
+	}, parent)
+
+	msg = ParseMsg("@badges=vip-ish/1,subscriber-gifter/1;display-name= :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #channel :hi")
+
+	assertEq(t, false, msg.IsVIP())
+	assertEq(t, false, msg.IsSub())
+	assertEq(t, "viewer", msg.DisplayName())
+	assertEq(t, []Emote(nil), msg.Emotes())
+
+	_, ok = msg.ReplyParent()
+	assertEq(t, false, ok)
+}
+
+func TestEvent(t *testing.T) {
+	e, ok := ParseMsg(cases[3].rawMsg).Event()
+	assertEq(t, true, ok)
+	assertEq(t, EventRaid, e.Type)
+	assertEq(t, "raider", e.User)
+	assertEq(t, 80, e.ViewerCount)
+
+	e, ok = ParseMsg(`@display-name=Gifter;login=gifter;msg-id=subgift;msg-param-months=3;msg-param-recipient-display-name=Lucky;msg-param-recipient-user-name=lucky;msg-param-sub-plan=2000 :tmi.twitch.tv USERNOTICE #channel`).Event()
+	assertEq(t, true, ok)
+	assertEq(t, &Event{
+		Type:                 EventSubGift,
+		User:                 "gifter",
+		DisplayName:          "Gifter",
+		Months:               3,
+		Tier:                 "2",
+		Recipient:            "lucky",
+		RecipientDisplayName: "Lucky",
+	}, e)
+
+	_, ok = ParseMsg(cases[4].rawMsg).Event()
+	assertEq(t, false, ok)
+}
+
 func BenchmarkParseMsg(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		ParseMsg(cases[4].rawMsg)
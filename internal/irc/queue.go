@@ -0,0 +1,246 @@
+package irc
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ihrk/microbot/internal/limit"
+)
+
+const (
+	rateWindow   = 30 * time.Second
+	rateLimit    = 20  // messages per window in channels where bot is not a mod
+	rateLimitMod = 100 // messages per window in total
+
+	queueCap = 100
+)
+
+const (
+	laneHigh = iota // moderation commands
+	laneLow         // chat messages
+	laneCount
+)
+
+var ErrQueueFull = errors.New("send queue is full")
+
+var modCmds = []string{
+	"/delete",
+	"/timeout",
+	"/ban",
+}
+
+type QueueStats struct {
+	Depth   int
+	Dropped uint64
+}
+
+type sendQueue struct {
+	m       sync.Mutex
+	lanes   [laneCount][]*Msg
+	depth   int
+	dropped uint64
+	mods    map[string]bool
+	normal  limit.Counter
+	global  limit.Counter
+	ready   chan struct{}
+	waiters []chan struct{} // closed when queue gets empty
+	sending *Msg            // head returned by peek, it is not evicted
+}
+
+func newSendQueue() *sendQueue {
+	return &sendQueue{
+		mods:   make(map[string]bool),
+		normal: limit.New(rateLimit, rateWindow),
+		global: limit.New(rateLimitMod, rateWindow),
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+func msgLane(msg *Msg) int {
+	cmd := msg.Text
+	if end := strings.IndexByte(cmd, ' '); end != -1 {
+		cmd = cmd[:end]
+	}
+
+	for _, modCmd := range modCmds {
+		if cmd == modCmd {
+			return laneHigh
+		}
+	}
+
+	return laneLow
+}
+
+// push appends msg to its lane, when queue is full chat messages
+// are dropped in favor of moderation commands.
+func (q *sendQueue) push(msg *Msg) error {
+	lane := msgLane(msg)
+
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	if q.depth >= queueCap {
+		q.dropped++
+
+		// the oldest chat message is evicted unless it is being sent
+		low := q.lanes[laneLow]
+		i := 0
+		if len(low) > 0 && low[0] == q.sending {
+			i = 1
+		}
+
+		if lane == laneLow || len(low) <= i {
+			return ErrQueueFull
+		}
+
+		q.lanes[laneLow] = append(low[:i:i], low[i+1:]...)
+		q.depth--
+	}
+
+	q.lanes[lane] = append(q.lanes[lane], msg)
+	q.depth++
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// adopt takes unsent messages, rate limits and mod status of channels
+// from queue of previous connection, because Twitch limits are per
+// account rather than per connection.
+func (q *sendQueue) adopt(prev *sendQueue) {
+	prev.m.Lock()
+	defer prev.m.Unlock()
+
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	for lane := range q.lanes {
+		q.lanes[lane] = append(prev.lanes[lane], q.lanes[lane]...)
+		prev.lanes[lane] = nil
+	}
+
+	q.depth += prev.depth
+	q.dropped += prev.dropped
+	prev.depth = 0
+	prev.dropped = 0
+
+	q.normal = prev.normal
+	q.global = prev.global
+
+	for channel, mod := range prev.mods {
+		if _, ok := q.mods[channel]; !ok {
+			q.mods[channel] = mod
+		}
+	}
+
+	// message prev was sending when connection was lost is sent again
+	prev.sending = nil
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// peek returns head of the first non-empty lane, it is kept
+// in queue until commit.
+func (q *sendQueue) peek() (*Msg, int, bool) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	for lane := range q.lanes {
+		if len(q.lanes[lane]) > 0 {
+			q.sending = q.lanes[lane][0]
+			return q.sending, lane, true
+		}
+	}
+
+	q.sending = nil
+
+	return nil, 0, false
+}
+
+// delay returns how long msg for channel has to wait to fit in rate limits.
+func (q *sendQueue) delay(channel string) time.Duration {
+	q.m.Lock()
+	mod := q.mods[channel]
+	normal, global := q.normal, q.global
+	q.m.Unlock()
+
+	d := global.Delay(1)
+
+	if !mod {
+		if nd := normal.Delay(1); nd > d {
+			d = nd
+		}
+	}
+
+	return d
+}
+
+// commit removes head of the lane after it was sent.
+func (q *sendQueue) commit(lane int) {
+	q.m.Lock()
+
+	msg := q.lanes[lane][0]
+	q.lanes[lane] = q.lanes[lane][1:]
+	q.depth--
+	q.sending = nil
+
+	if q.depth == 0 {
+		for _, w := range q.waiters {
+			close(w)
+		}
+
+		q.waiters = nil
+	}
+
+	mod := q.mods[msg.Channel]
+	normal, global := q.normal, q.global
+
+	q.m.Unlock()
+
+	global.Add(1)
+
+	if !mod {
+		normal.Add(1)
+	}
+}
+
+// empty returns channel which is closed when all messages are sent.
+func (q *sendQueue) empty() <-chan struct{} {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	w := make(chan struct{})
+
+	if q.depth == 0 {
+		close(w)
+	} else {
+		q.waiters = append(q.waiters, w)
+	}
+
+	return w
+}
+
+func (q *sendQueue) setMod(channel string, mod bool) {
+	q.m.Lock()
+	q.mods[channel] = mod
+	q.m.Unlock()
+}
+
+func (q *sendQueue) stats() QueueStats {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return QueueStats{
+		Depth:   q.depth,
+		Dropped: q.dropped,
+	}
+}
@@ -0,0 +1,156 @@
+package irc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func lowMsg(i int) *Msg {
+	return NewPrivMsg("channel", fmt.Sprintf("msg %d", i))
+}
+
+func highMsg(i int) *Msg {
+	return NewPrivMsg("channel", fmt.Sprintf("/delete %d", i))
+}
+
+// queued returns texts of messages that are in queue.
+func queued(q *sendQueue) map[string]bool {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	texts := make(map[string]bool)
+
+	for _, lane := range q.lanes {
+		for _, msg := range lane {
+			texts[msg.Text] = true
+		}
+	}
+
+	return texts
+}
+
+// send sends all queued messages like writeLoop does.
+func send(q *sendQueue) []string {
+	var sent []string
+
+	for {
+		msg, lane, ok := q.peek()
+		if !ok {
+			return sent
+		}
+
+		sent = append(sent, msg.Text)
+		q.commit(lane)
+	}
+}
+
+// checkOverflow pushes msgs while write of inFlight is blocked,
+// then checks that every message is either sent or dropped.
+func checkOverflow(t *testing.T, q *sendQueue, inFlight *Msg, lane int, msgs []*Msg) {
+	t.Helper()
+
+	pushed := []string{inFlight.Text}
+
+	for _, msg := range msgs {
+		pushed = append(pushed, msg.Text)
+		q.push(msg)
+	}
+
+	kept := queued(q)
+
+	// write of inFlight succeeds
+	q.commit(lane)
+
+	sent := append([]string{inFlight.Text}, send(q)...)
+
+	isSent := make(map[string]bool)
+	for _, text := range sent {
+		if isSent[text] {
+			t.Errorf("message is sent twice: %s", text)
+		}
+
+		isSent[text] = true
+	}
+
+	var dropped int
+
+	for _, text := range pushed {
+		if kept[text] || text == inFlight.Text && isSent[text] {
+			if !isSent[text] {
+				t.Errorf("queued message is lost: %s", text)
+			}
+
+			continue
+		}
+
+		dropped++
+
+		if isSent[text] {
+			t.Errorf("message is both dropped and sent: %s", text)
+		}
+	}
+
+	assertEq(t, QueueStats{Depth: 0, Dropped: uint64(dropped)}, q.stats())
+}
+
+func TestQueueOverflowWhileSending(t *testing.T) {
+	q := newSendQueue()
+
+	assertEq(t, nil, q.push(lowMsg(0)))
+
+	inFlight, lane, _ := q.peek()
+
+	var msgs []*Msg
+
+	for i := 1; i < queueCap; i++ {
+		msgs = append(msgs, lowMsg(i))
+	}
+
+	msgs = append(msgs, highMsg(0), lowMsg(queueCap), highMsg(1))
+
+	checkOverflow(t, q, inFlight, lane, msgs)
+}
+
+func TestQueueOverflowWithSingleChatMessage(t *testing.T) {
+	q := newSendQueue()
+
+	assertEq(t, nil, q.push(lowMsg(0)))
+
+	inFlight, lane, _ := q.peek()
+
+	var msgs []*Msg
+
+	// the only chat message is being sent, so mod commands
+	// over capacity can not evict it
+	for i := 0; i <= queueCap; i++ {
+		msgs = append(msgs, highMsg(i))
+	}
+
+	checkOverflow(t, q, inFlight, lane, msgs)
+}
+
+func TestQueueAdoptKeepsLimits(t *testing.T) {
+	prev := newSendQueue()
+	prev.setMod("modded", true)
+
+	for i := 0; i < rateLimit; i++ {
+		assertEq(t, nil, prev.push(lowMsg(i)))
+	}
+
+	send(prev)
+
+	assertEq(t, nil, prev.push(lowMsg(rateLimit)))
+
+	q := newSendQueue()
+	q.adopt(prev)
+
+	assertEq(t, QueueStats{Depth: 1}, q.stats())
+
+	// limits are per account, so new connection has to wait
+	if d := q.delay("channel"); d <= 0 {
+		t.Errorf("expected delay in channel where bot is not a mod, got: %v", d)
+	}
+
+	assertEq(t, time.Duration(0), q.delay("modded"))
+}
@@ -0,0 +1,240 @@
+package irc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	BadgeBroadcaster = "broadcaster"
+	BadgeModerator   = "moderator"
+	BadgeVIP         = "vip"
+	BadgeSubscriber  = "subscriber"
+	BadgeFounder     = "founder"
+)
+
+type Badge struct {
+	Name    string
+	Version string
+}
+
+type Badges []Badge
+
+func parseBadges(rawBadges string) Badges {
+	if rawBadges == "" {
+		return nil
+	}
+
+	pairs := strings.Split(rawBadges, ",")
+
+	badges := make(Badges, 0, len(pairs))
+
+	for _, pair := range pairs {
+		var b Badge
+
+		if sepOff := strings.IndexByte(pair, '/'); sepOff != -1 {
+			b.Name = pair[:sepOff]
+			b.Version = pair[sepOff+1:]
+		} else {
+			b.Name = pair
+		}
+
+		badges = append(badges, b)
+	}
+
+	return badges
+}
+
+func (b Badges) Has(name string) bool {
+	_, ok := b.Version(name)
+	return ok
+}
+
+func (b Badges) Version(name string) (string, bool) {
+	for i := range b {
+		if b[i].Name == name {
+			return b[i].Version, true
+		}
+	}
+
+	return "", false
+}
+
+func (msg *Msg) Badges() Badges {
+	return parseBadges(msg.Tags["badges"])
+}
+
+// BadgeInfo contains precise badge versions, e.g. exact number
+// of subscription months.
+func (msg *Msg) BadgeInfo() Badges {
+	return parseBadges(msg.Tags["badge-info"])
+}
+
+// SubMonths returns number of months user has been subscribed for,
+// it is zero for users without subscription.
+func (msg *Msg) SubMonths() int {
+	info := msg.BadgeInfo()
+
+	v, ok := info.Version(BadgeSubscriber)
+	if !ok {
+		v, _ = info.Version(BadgeFounder)
+	}
+
+	n, _ := strconv.Atoi(v)
+
+	return n
+}
+
+func (msg *Msg) IsBroadcaster() bool {
+	return msg.Badges().Has(BadgeBroadcaster)
+}
+
+func (msg *Msg) IsMod() bool {
+	return msg.Badges().Has(BadgeModerator)
+}
+
+func (msg *Msg) IsVIP() bool {
+	return msg.Badges().Has(BadgeVIP)
+}
+
+// IsSub reports whether user is subscriber, founders lose
+// subscriber badge in favor of founder one.
+func (msg *Msg) IsSub() bool {
+	badges := msg.Badges()
+	return badges.Has(BadgeSubscriber) || badges.Has(BadgeFounder)
+}
+
+// Emote is an occurrence of twitch emote in message text,
+// Start and End are inclusive rune offsets.
+type Emote struct {
+	ID    string
+	Start int
+	End   int
+}
+
+// Name returns emote code from message text.
+func (e Emote) Name(text string) string {
+	runes := []rune(text)
+	if e.Start < 0 || e.End >= len(runes) || e.Start > e.End {
+		return ""
+	}
+
+	return string(runes[e.Start : e.End+1])
+}
+
+// Emotes returns emote occurrences ordered by position in text.
+func (msg *Msg) Emotes() []Emote {
+	rawEmotes := msg.Tags["emotes"]
+	if rawEmotes == "" {
+		return nil
+	}
+
+	var emotes []Emote
+
+	for _, rawEmote := range strings.Split(rawEmotes, "/") {
+		sepOff := strings.IndexByte(rawEmote, ':')
+		if sepOff == -1 {
+			continue
+		}
+
+		id := rawEmote[:sepOff]
+
+		for _, rawRange := range strings.Split(rawEmote[sepOff+1:], ",") {
+			dashOff := strings.IndexByte(rawRange, '-')
+			if dashOff == -1 {
+				continue
+			}
+
+			start, err := strconv.Atoi(rawRange[:dashOff])
+			if err != nil {
+				continue
+			}
+
+			end, err := strconv.Atoi(rawRange[dashOff+1:])
+			if err != nil {
+				continue
+			}
+
+			emotes = append(emotes, Emote{id, start, end})
+		}
+	}
+
+	sort.Slice(emotes, func(i, j int) bool {
+		return emotes[i].Start < emotes[j].Start
+	})
+
+	return emotes
+}
+
+func (msg *Msg) ID() string {
+	return msg.Tags["id"]
+}
+
+func (msg *Msg) UserID() string {
+	return msg.Tags["user-id"]
+}
+
+func (msg *Msg) RoomID() string {
+	return msg.Tags["room-id"]
+}
+
+// DisplayName falls back to login if display name is not set.
+func (msg *Msg) DisplayName() string {
+	if name := msg.Tags["display-name"]; name != "" {
+		return name
+	}
+
+	return msg.User
+}
+
+func (msg *Msg) Color() string {
+	return msg.Tags["color"]
+}
+
+func (msg *Msg) RewardID() string {
+	return msg.Tags["custom-reward-id"]
+}
+
+// FirstMsg reports whether it is the first message of user in the channel.
+func (msg *Msg) FirstMsg() bool {
+	return msg.Tags["first-msg"] == "1"
+}
+
+func (msg *Msg) ReturningChatter() bool {
+	return msg.Tags["returning-chatter"] == "1"
+}
+
+// Bits returns amount of cheered bits.
+func (msg *Msg) Bits() int {
+	return msg.intTag("bits")
+}
+
+// ReplyParent describes message that msg replies to.
+type ReplyParent struct {
+	MsgID       string
+	UserID      string
+	UserLogin   string
+	DisplayName string
+	Body        string
+}
+
+func (msg *Msg) ReplyParent() (ReplyParent, bool) {
+	id, ok := msg.Tags["reply-parent-msg-id"]
+	if !ok {
+		return ReplyParent{}, false
+	}
+
+	return ReplyParent{
+		MsgID:       id,
+		UserID:      msg.Tags["reply-parent-user-id"],
+		UserLogin:   msg.Tags["reply-parent-user-login"],
+		DisplayName: msg.Tags["reply-parent-display-name"],
+		Body:        msg.Tags["reply-parent-msg-body"],
+	}, true
+}
+
+func (msg *Msg) intTag(name string) int {
+	n, _ := strconv.Atoi(msg.Tags[name])
+	return n
+}
@@ -23,6 +23,8 @@ type node struct {
 
 type Counter interface {
 	Add(val int) bool
+	// Delay returns how long to wait until Add(val) succeeds.
+	Delay(val int) time.Duration
 }
 
 func New(lim int, per time.Duration) Counter {
@@ -71,3 +73,28 @@ func (l *counter) Add(val int) (ok bool) {
 
 	return
 }
+
+func (l *counter) Delay(val int) (d time.Duration) {
+	l.m.Lock()
+
+	now := unixtime.Now()
+
+	l.cleanup(now)
+
+	if l.cur+val > l.lim {
+		d = l.per
+
+		freed := 0
+		for i := l.off; i < len(l.nodes); i++ {
+			freed += l.nodes[i].val
+			if l.cur-freed+val <= l.lim {
+				d = l.nodes[i].exp.Sub(now) + 1
+				break
+			}
+		}
+	}
+
+	l.m.Unlock()
+
+	return
+}
@@ -0,0 +1,55 @@
+package limit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounterDelay(t *testing.T) {
+	const per = time.Hour
+
+	c := New(3, per)
+
+	if d := c.Delay(1); d != 0 {
+		t.Errorf("empty counter delay: %v", d)
+	}
+
+	if !c.Add(2) || !c.Add(1) {
+		t.Fatal("values within limit are not added")
+	}
+
+	if c.Add(1) {
+		t.Error("value over limit is added")
+	}
+
+	// the first value has to expire to fit 1 or 2
+	for _, val := range []int{1, 2} {
+		if d := c.Delay(val); d <= per-time.Minute || d > per+time.Second {
+			t.Errorf("delay of %d: %v", val, d)
+		}
+	}
+
+	// more than limit never fits, the whole period is returned
+	if d := c.Delay(4); d != per {
+		t.Errorf("delay over limit: %v", d)
+	}
+}
+
+func TestCounterExpiry(t *testing.T) {
+	c := New(1, 10*time.Millisecond)
+
+	if !c.Add(1) {
+		t.Fatal("value within limit is not added")
+	}
+
+	d := c.Delay(1)
+	if d <= 0 {
+		t.Fatalf("expected delay, got: %v", d)
+	}
+
+	time.Sleep(d)
+
+	if !c.Add(1) {
+		t.Error("value is not added after delay")
+	}
+}
@@ -0,0 +1,197 @@
+// Package record writes raw chat lines with timestamps to rotating
+// files and reads them back for replay.
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxSize  = 100 << 20
+	defaultMaxFiles = 5
+)
+
+// Entry is a single line of record.
+type Entry struct {
+	Time time.Time
+	Line string
+}
+
+// Writer appends lines to file at path, when file grows over maxSize
+// it is renamed to path.1, path.1 to path.2 and so on, the oldest
+// file is overwritten when there are maxFiles of them.
+type Writer struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	m    sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open opens file for appending, zero maxSize and maxFiles
+// are set to 100 MiB and 5.
+func Open(path string, maxSize int64, maxFiles int) (*Writer, error) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+
+	if maxFiles <= 0 {
+		maxFiles = defaultMaxFiles
+	}
+
+	w := &Writer{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.f = f
+	w.size = fi.Size()
+
+	return nil
+}
+
+// Record writes line with current time, errors are logged
+// because reading of chat must not stop because of them.
+func (w *Writer) Record(line string) {
+	err := w.Write(Entry{Time: time.Now(), Line: line})
+	if err != nil {
+		log.Printf("record error: %v\n", err)
+	}
+}
+
+func (w *Writer) Write(e Entry) error {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if w.f == nil {
+		return os.ErrClosed
+	}
+
+	if w.size >= w.maxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := fmt.Fprintf(w.f, "%s %s\n", e.Time.UTC().Format(time.RFC3339Nano), e.Line)
+	w.size += int64(n)
+
+	return err
+}
+
+func (w *Writer) rotate() error {
+	err := w.f.Close()
+	if err != nil {
+		return err
+	}
+
+	w.f = nil
+
+	for i := w.maxFiles - 1; i > 0; i-- {
+		// rename replaces the oldest file
+		err = os.Rename(w.backup(i), w.backup(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	err = os.Rename(w.path, w.backup(1))
+	if err != nil {
+		return err
+	}
+
+	return w.open()
+}
+
+func (w *Writer) backup(i int) string {
+	return fmt.Sprintf("%s.%d", w.path, i)
+}
+
+func (w *Writer) Close() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if w.f == nil {
+		return nil
+	}
+
+	err := w.f.Close()
+	w.f = nil
+
+	return err
+}
+
+// Reader reads entries written by Writer.
+type Reader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+const maxLineSize = 64 << 10
+
+func NewReader(r io.Reader) *Reader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxLineSize)
+
+	return &Reader{sc: sc}
+}
+
+// Next returns the next entry, io.EOF is returned at the end.
+func (r *Reader) Next() (Entry, error) {
+	for r.sc.Scan() {
+		r.line++
+
+		text := r.sc.Text()
+		if text == "" {
+			continue
+		}
+
+		sep := strings.IndexByte(text, ' ')
+		if sep == -1 {
+			return Entry{}, fmt.Errorf("line %d: timestamp not found", r.line)
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, text[:sep])
+		if err != nil {
+			return Entry{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+
+		return Entry{Time: t, Line: text[sep+1:]}, nil
+	}
+
+	if err := r.sc.Err(); err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{}, io.EOF
+}
@@ -0,0 +1,66 @@
+package record
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func assertEq(t *testing.T, expected, actual interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nexpected: '%v',\nactual: '%v'", expected, actual)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.log")
+
+	w, err := Open(path, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2022, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	lines := []string{"first", "second", "third", "fourth"}
+
+	for i, line := range lines {
+		err = w.Write(Entry{Time: start.Add(time.Duration(i) * time.Second), Line: line})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every line exceeds max size, so each of them is in own file
+	// and the first one is removed
+	for i, name := range []string{path + ".2", path + ".1", path} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := NewReader(f)
+
+		e, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertEq(t, Entry{Time: start.Add(time.Duration(i+1) * time.Second), Line: lines[i+1]}, e)
+
+		_, err = r.Next()
+		assertEq(t, io.EOF, err)
+
+		f.Close()
+	}
+}
@@ -0,0 +1,31 @@
+package store
+
+import "strconv"
+
+const counterPrefix = "counter/"
+
+// Counter returns value of named counter, missing counter is zero.
+func Counter(s Store, name string) int64 {
+	v, _ := s.Get(counterPrefix + name)
+	n, _ := strconv.ParseInt(v, 10, 64)
+
+	return n
+}
+
+// AddCounter increments named counter by delta and returns new value.
+func AddCounter(s Store, name string, delta int64) (int64, error) {
+	var n int64
+
+	err := s.Update(counterPrefix+name, func(v string, _ bool) (string, error) {
+		n, _ = strconv.ParseInt(v, 10, 64)
+		n += delta
+
+		return strconv.FormatInt(n, 10), nil
+	})
+
+	return n, err
+}
+
+func SetCounter(s Store, name string, n int64) error {
+	return s.Set(counterPrefix+name, strconv.FormatInt(n, 10))
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// minCompactSize is the number of records in log before compaction
+// is considered at all.
+const minCompactSize = 1000
+
+type fileBackend struct {
+	f *os.File
+}
+
+// Open loads append-only log of records from path, file is created
+// if it does not exist. Log is compacted on open when most of its
+// records are outdated.
+func Open(path string) (DB, error) {
+	d := newDB(nopBackend{})
+
+	n, err := load(d, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > minCompactSize && n > 2*d.size() {
+		err = compact(d, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	d.b = &fileBackend{f}
+
+	return d, nil
+}
+
+// load applies records from file and returns their number,
+// incomplete last line left by crash is cut off.
+func load(d *db, path string) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	defer f.Close()
+
+	rd := bufio.NewReader(f)
+
+	var (
+		n   int
+		off int64
+	)
+
+	for {
+		line, err := rd.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				return n, os.Truncate(path, off)
+			}
+
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		off += int64(len(line))
+
+		var r record
+
+		err = json.Unmarshal(line, &r)
+		if err != nil {
+			return n, fmt.Errorf("%s:%d: %w", path, n+1, err)
+		}
+
+		d.apply(r)
+		n++
+	}
+}
+
+func compact(d *db, path string) error {
+	tmpPath := path + ".tmp"
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	for ns, values := range d.data {
+		for k, v := range values {
+			err = enc.Encode(record{NS: ns, Key: k, Value: v})
+			if err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func (d *db) size() int {
+	var n int
+
+	for _, values := range d.data {
+		n += len(values)
+	}
+
+	return n
+}
+
+func (b *fileBackend) write(r record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.f.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return b.f.Sync()
+}
+
+func (b *fileBackend) close() error {
+	return b.f.Close()
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store is a key-value storage of a single namespace.
+type Store interface {
+	Get(key string) (string, bool)
+	Set(key, value string) error
+	Delete(key string) error
+	// Keys returns sorted keys with prefix.
+	Keys(prefix string) []string
+	// Update atomically replaces value of key with result of f,
+	// ok is false if key is not set.
+	Update(key string, f func(value string, ok bool) (string, error)) error
+}
+
+// DB is a set of stores, usually one per channel.
+type DB interface {
+	Namespace(name string) Store
+	Close() error
+}
+
+// ErrClosed is returned by writes after DB is closed,
+// e.g. by handlers that outlive shutdown.
+var ErrClosed = errors.New("store is closed")
+
+type record struct {
+	NS      string `json:"ns"`
+	Key     string `json:"key"`
+	Value   string `json:"value,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+type backend interface {
+	write(r record) error
+	close() error
+}
+
+type db struct {
+	m      sync.Mutex
+	data   map[string]map[string]string
+	b      backend
+	closed bool
+}
+
+// NewMemory returns DB which state is lost on exit.
+func NewMemory() DB {
+	return newDB(nopBackend{})
+}
+
+func newDB(b backend) *db {
+	return &db{
+		data: make(map[string]map[string]string),
+		b:    b,
+	}
+}
+
+func (d *db) apply(r record) {
+	ns, ok := d.data[r.NS]
+	if !ok {
+		if r.Deleted {
+			return
+		}
+
+		ns = make(map[string]string)
+		d.data[r.NS] = ns
+	}
+
+	if r.Deleted {
+		delete(ns, r.Key)
+	} else {
+		ns[r.Key] = r.Value
+	}
+}
+
+// commit writes record to backend before applying it,
+// so memory state never gets ahead of disk.
+func (d *db) commit(r record) error {
+	if d.closed {
+		return ErrClosed
+	}
+
+	if err := d.b.write(r); err != nil {
+		return err
+	}
+
+	d.apply(r)
+
+	return nil
+}
+
+func (d *db) Namespace(name string) Store {
+	return &namespace{d, name}
+}
+
+func (d *db) Close() error {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if d.closed {
+		return nil
+	}
+
+	d.closed = true
+
+	return d.b.close()
+}
+
+type namespace struct {
+	d    *db
+	name string
+}
+
+func (n *namespace) Get(key string) (string, bool) {
+	n.d.m.Lock()
+	defer n.d.m.Unlock()
+
+	v, ok := n.d.data[n.name][key]
+
+	return v, ok
+}
+
+func (n *namespace) Set(key, value string) error {
+	n.d.m.Lock()
+	defer n.d.m.Unlock()
+
+	return n.d.commit(record{NS: n.name, Key: key, Value: value})
+}
+
+func (n *namespace) Delete(key string) error {
+	n.d.m.Lock()
+	defer n.d.m.Unlock()
+
+	if _, ok := n.d.data[n.name][key]; !ok {
+		return nil
+	}
+
+	return n.d.commit(record{NS: n.name, Key: key, Deleted: true})
+}
+
+func (n *namespace) Keys(prefix string) []string {
+	n.d.m.Lock()
+
+	var keys []string
+
+	for k := range n.d.data[n.name] {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+
+	n.d.m.Unlock()
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (n *namespace) Update(
+	key string,
+	f func(value string, ok bool) (string, error),
+) error {
+	n.d.m.Lock()
+	defer n.d.m.Unlock()
+
+	old, ok := n.d.data[n.name][key]
+
+	v, err := f(old, ok)
+	if err != nil {
+		return err
+	}
+
+	return n.d.commit(record{NS: n.name, Key: key, Value: v})
+}
+
+type nopBackend struct{}
+
+func (nopBackend) write(_ record) error { return nil }
+
+func (nopBackend) close() error { return nil }
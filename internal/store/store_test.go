@@ -0,0 +1,80 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func assertEq(t *testing.T, expected, actual interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nexpected: '%v',\nactual: '%v'", expected, actual)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := db.Namespace("first"), db.Namespace("second")
+
+	assertEq(t, nil, first.Set("quote/1", "one"))
+	assertEq(t, nil, first.Set("quote/2", "two"))
+	assertEq(t, nil, first.Delete("quote/1"))
+	assertEq(t, nil, second.Set("quote/1", "other"))
+
+	n, err := AddCounter(first, "deaths", 3)
+	assertEq(t, nil, err)
+	assertEq(t, int64(3), n)
+
+	assertEq(t, nil, db.Close())
+
+	// simulate record torn by crash
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"ns":"first","key":"tor`)
+	f.Close()
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second = db.Namespace("first"), db.Namespace("second")
+
+	assertEq(t, []string{"quote/2"}, first.Keys("quote/"))
+	assertEq(t, int64(3), Counter(first, "deaths"))
+
+	v, ok := second.Get("quote/1")
+	assertEq(t, true, ok)
+	assertEq(t, "other", v)
+
+	assertEq(t, nil, SetCounter(first, "deaths", 10))
+	assertEq(t, nil, db.Close())
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first = db.Namespace("first")
+
+	assertEq(t, int64(10), Counter(first, "deaths"))
+	assertEq(t, nil, db.Close())
+
+	// late writes of handlers are refused instead of being lost
+	_, err = AddCounter(first, "deaths", 1)
+	assertEq(t, ErrClosed, err)
+	assertEq(t, ErrClosed, first.Set("quote/3", "three"))
+	assertEq(t, int64(10), Counter(first, "deaths"))
+	assertEq(t, nil, db.Close())
+}
@@ -0,0 +1,304 @@
+package tmpl
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ihrk/microbot/internal/irc"
+)
+
+// Template is a text with placeholders in curly braces, e.g. "{user}"
+// or "{random:a|b|c}", literal braces are escaped by doubling them.
+type Template struct {
+	parts []part
+}
+
+type part struct {
+	text string
+	fn   func(d *Data, arg string) string
+	arg  string
+}
+
+// Data is used to render placeholders, Vars contains values
+// of extra placeholders passed to Parse.
+type Data struct {
+	Msg      *irc.Msg
+	Counters Counters
+	Vars     map[string]string
+}
+
+// Counters holds named counters of the channel.
+type Counters interface {
+	Add(name string, delta int64) int64
+}
+
+type placeholder struct {
+	fn      func(d *Data, arg string) string
+	needArg bool
+}
+
+var placeholders = map[string]placeholder{
+	"user":      {fn: user},
+	"name":      {fn: displayName},
+	"channel":   {fn: channel},
+	"args":      {fn: allArgs},
+	"touser":    {fn: toUser},
+	"input":     {fn: input},
+	"bits":      {fn: bits},
+	"time":      {fn: timeNow},
+	"random":    {fn: random, needArg: true},
+	"count":     {fn: count},
+	"months":    {fn: months},
+	"tier":      {fn: tier},
+	"recipient": {fn: recipient},
+	"gifts":     {fn: gifts},
+	"viewers":   {fn: viewers},
+}
+
+// Parse compiles text, vars are names of extra placeholders
+// which values are provided with Data.Vars.
+func Parse(text string, vars ...string) (*Template, error) {
+	var (
+		t   Template
+		lit strings.Builder
+	)
+
+	flush := func() {
+		if lit.Len() > 0 {
+			t.parts = append(t.parts, part{text: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		switch {
+		case (c == '{' || c == '}') && i+1 < len(text) && text[i+1] == c:
+			lit.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed placeholder at %d", i)
+			}
+
+			p, err := newPart(text[i+1:i+end], vars)
+			if err != nil {
+				return nil, err
+			}
+
+			flush()
+			t.parts = append(t.parts, p)
+
+			i += end
+		case c == '}':
+			return nil, fmt.Errorf("unexpected '}' at %d", i)
+		default:
+			lit.WriteByte(c)
+		}
+	}
+
+	flush()
+
+	return &t, nil
+}
+
+func newPart(expr string, vars []string) (part, error) {
+	name, arg := expr, ""
+	hasArg := false
+
+	if sepOff := strings.IndexByte(expr, ':'); sepOff != -1 {
+		name, arg = expr[:sepOff], expr[sepOff+1:]
+		hasArg = true
+	}
+
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 1 || hasArg {
+			return part{}, fmt.Errorf("invalid argument placeholder: {%s}", expr)
+		}
+
+		return part{fn: argByIndex, arg: name}, nil
+	}
+
+	for _, v := range vars {
+		if name == v && !hasArg {
+			return part{fn: variable, arg: name}, nil
+		}
+	}
+
+	p, ok := placeholders[name]
+	if !ok {
+		return part{}, fmt.Errorf("unknown placeholder: {%s}", expr)
+	}
+
+	if p.needArg && arg == "" {
+		return part{}, fmt.Errorf("placeholder requires argument: {%s}", expr)
+	}
+
+	return part{fn: p.fn, arg: arg}, nil
+}
+
+func (t *Template) Execute(d *Data) string {
+	var sb strings.Builder
+
+	for _, p := range t.parts {
+		if p.fn == nil {
+			sb.WriteString(p.text)
+		} else {
+			sb.WriteString(p.fn(d, p.arg))
+		}
+	}
+
+	return sb.String()
+}
+
+// args returns words of message without command.
+func args(msg *irc.Msg) []string {
+	words := strings.Fields(msg.Text)
+	if len(words) > 0 && strings.HasPrefix(words[0], "!") {
+		words = words[1:]
+	}
+
+	return words
+}
+
+func variable(d *Data, name string) string {
+	return d.Vars[name]
+}
+
+func argByIndex(d *Data, arg string) string {
+	n, _ := strconv.Atoi(arg)
+
+	words := args(d.Msg)
+	if n > len(words) {
+		return ""
+	}
+
+	return words[n-1]
+}
+
+func user(d *Data, _ string) string {
+	if e, ok := d.Msg.Event(); ok {
+		return e.User
+	}
+
+	return d.Msg.User
+}
+
+func displayName(d *Data, _ string) string {
+	if e, ok := d.Msg.Event(); ok {
+		return e.DisplayName
+	}
+
+	return d.Msg.DisplayName()
+}
+
+func channel(d *Data, _ string) string {
+	return d.Msg.Channel
+}
+
+func allArgs(d *Data, _ string) string {
+	return strings.Join(args(d.Msg), " ")
+}
+
+// toUser returns first argument without @, or sender name
+// if there are no arguments.
+func toUser(d *Data, _ string) string {
+	if words := args(d.Msg); len(words) > 0 {
+		return strings.TrimPrefix(words[0], "@")
+	}
+
+	return displayName(d, "")
+}
+
+func input(d *Data, _ string) string {
+	return d.Msg.Text
+}
+
+func bits(d *Data, _ string) string {
+	return strconv.Itoa(d.Msg.Bits())
+}
+
+const defaultTimeLayout = "15:04"
+
+func timeNow(_ *Data, layout string) string {
+	if layout == "" {
+		layout = defaultTimeLayout
+	}
+
+	return time.Now().Format(layout)
+}
+
+var (
+	rndMu sync.Mutex
+	rnd   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func random(_ *Data, arg string) string {
+	choices := strings.Split(arg, "|")
+
+	rndMu.Lock()
+	i := rnd.Intn(len(choices))
+	rndMu.Unlock()
+
+	return choices[i]
+}
+
+const defaultCounter = "count"
+
+// count increments named counter, by default counter is named
+// after the command.
+func count(d *Data, name string) string {
+	if name == "" {
+		name = defaultCounter
+
+		words := strings.Fields(d.Msg.Text)
+		if len(words) > 0 && strings.HasPrefix(words[0], "!") {
+			name = words[0][1:]
+		}
+	}
+
+	if d.Counters == nil {
+		return "0"
+	}
+
+	return strconv.FormatInt(d.Counters.Add(name, 1), 10)
+}
+
+func event(d *Data) *irc.Event {
+	if e, ok := d.Msg.Event(); ok {
+		return e
+	}
+
+	return &irc.Event{}
+}
+
+// months returns subscription months of the event or of the sender.
+func months(d *Data, _ string) string {
+	if e, ok := d.Msg.Event(); ok {
+		return strconv.Itoa(e.Months)
+	}
+
+	return strconv.Itoa(d.Msg.SubMonths())
+}
+
+func tier(d *Data, _ string) string {
+	return event(d).Tier
+}
+
+func recipient(d *Data, _ string) string {
+	return event(d).RecipientDisplayName
+}
+
+func gifts(d *Data, _ string) string {
+	return strconv.Itoa(event(d).GiftCount)
+}
+
+func viewers(d *Data, _ string) string {
+	return strconv.Itoa(event(d).ViewerCount)
+}
@@ -0,0 +1,90 @@
+package tmpl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ihrk/microbot/internal/irc"
+)
+
+func TestExecute(t *testing.T) {
+	msg := irc.ParseMsg("@badge-info=subscriber/5;display-name=Viewer :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #channel :!hug @friend  now")
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Welcome {user}, you said {args}", "Welcome viewer, you said @friend now"},
+		{"{touser} has been hugged {count} times", "friend has been hugged 1 times"},
+		{"{touser} has been hugged {count:hug} times", "friend has been hugged 2 times"},
+		{"{name} in {channel}: {1}|{2}|{3}", "Viewer in channel: @friend|now|"},
+		{"{months} months {{literal}}", "5 months {literal}"},
+		{"{random:only}", "only"},
+		{"{extra}", "value"},
+	}
+
+	counters := testCounters{}
+
+	for _, tc := range tests {
+		tt, err := Parse(tc.text, "extra")
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.text, err)
+		}
+
+		actual := tt.Execute(&Data{
+			Msg:      msg,
+			Counters: counters,
+			Vars:     map[string]string{"extra": "value"},
+		})
+
+		if actual != tc.expected {
+			t.Errorf("\nexpected: '%v',\nactual: '%v'", tc.expected, actual)
+		}
+	}
+}
+
+type testCounters map[string]int64
+
+func (c testCounters) Add(name string, delta int64) int64 {
+	c[name] += delta
+	return c[name]
+}
+
+func TestEventPlaceholders(t *testing.T) {
+	msg := irc.ParseMsg("@display-name=Gifter;login=gifter;msg-id=subgift;msg-param-months=3;msg-param-recipient-display-name=Lucky;msg-param-sub-plan=1000 :tmi.twitch.tv USERNOTICE #channel")
+
+	tt, err := Parse("Thanks {name} for gifting tier {tier} sub to {recipient}, {months} months!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Thanks Gifter for gifting tier 1 sub to Lucky, 3 months!"
+	if actual := tt.Execute(&Data{Msg: msg}); actual != expected {
+		t.Errorf("\nexpected: '%v',\nactual: '%v'", expected, actual)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		"{unknown}",
+		"{user",
+		"user}",
+		"{random}",
+		"{0}",
+		"{extra:arg}",
+	} {
+		_, err := Parse(text, "extra")
+		if err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+
+	tt, err := Parse("{time:2006}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := tt.Execute(&Data{Msg: &irc.Msg{}}); len(s) != 4 || strings.Trim(s, "0123456789") != "" {
+		t.Errorf("unexpected year: %q", s)
+	}
+}